@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"log/slog"
 
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/cmd"
 	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
 	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/utils"
 	rolloutsPlugin "github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin/rpc"
@@ -22,13 +32,90 @@ var handshakeConfig = goPlugin.HandshakeConfig{
 }
 
 var lvl = flag.Int("l", int(slog.LevelInfo), "the logging level for 'log/slog', (default: 0)")
+var failOnMissingPermissions = flag.Bool("fail-on-missing-permissions", false, "fail the plugin initialization, or the canary steps of a rollout, when the plugin lacks RBAC permissions they need")
+var sweepOrphanRoutes = flag.Bool("sweep-orphan-routes", false, "delete, when the plugin starts, the routes it created for rollouts that no longer exist, which needs cluster-wide access to routes and rollouts")
+var debugAddress = flag.String("debug-address", os.Getenv("OPENSHIFT_PLUGIN_DEBUG_ADDRESS"), "address serving the health, state, log level and pprof endpoints, e.g. 127.0.0.1:6060, disabled when empty (env: OPENSHIFT_PLUGIN_DEBUG_ADDRESS)")
+var configFile = flag.String("config", os.Getenv("OPENSHIFT_PLUGIN_CONFIG"), "path of the plugin configuration file, reloaded when it changes (env: OPENSHIFT_PLUGIN_CONFIG)")
+
+// configPollInterval is how often the plugin configuration file is checked for changes
+const configPollInterval = 10 * time.Second
+
+// The client options may also be set through environment variables,
+// which is easier than flags when the plugin is started by the rollouts controller.
+var (
+	kubeconfig        = flag.String("kubeconfig", os.Getenv("OPENSHIFT_PLUGIN_KUBECONFIG"), "path of the kubeconfig file; when empty, $KUBECONFIG, then ~/.kube/config, then the in-cluster config are used (env: OPENSHIFT_PLUGIN_KUBECONFIG)")
+	kubeContext       = flag.String("context", os.Getenv("OPENSHIFT_PLUGIN_CONTEXT"), "the kubeconfig context to use (env: OPENSHIFT_PLUGIN_CONTEXT)")
+	qps               = flag.Float64("qps", envFloat("OPENSHIFT_PLUGIN_QPS"), "maximum queries per second to the API server (env: OPENSHIFT_PLUGIN_QPS)")
+	burst             = flag.Int("burst", envInt("OPENSHIFT_PLUGIN_BURST"), "maximum burst of queries to the API server (env: OPENSHIFT_PLUGIN_BURST)")
+	userAgent         = flag.String("user-agent", envOrDefault("OPENSHIFT_PLUGIN_USER_AGENT", "rollouts-plugin-trafficrouter-openshift"), "the user agent sent to the API server (env: OPENSHIFT_PLUGIN_USER_AGENT)")
+	impersonate       = flag.String("as", os.Getenv("OPENSHIFT_PLUGIN_AS"), "user to impersonate, e.g. system:serviceaccount:<namespace>:<name> (env: OPENSHIFT_PLUGIN_AS)")
+	impersonateGroups = flag.String("as-groups", os.Getenv("OPENSHIFT_PLUGIN_AS_GROUPS"), "comma separated groups to impersonate (env: OPENSHIFT_PLUGIN_AS_GROUPS)")
+)
 
 func main() {
+	if len(os.Args) > 1 {
+		if c, ok := cmd.Lookup(os.Args[1]); ok {
+			if err := c.Run(os.Args[2:], os.Stdout, os.Stderr); err != nil {
+				if !errors.Is(err, flag.ErrHelp) && !errors.Is(err, cmd.ErrFailed) {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), cmd.Usage())
+	}
 	flag.Parse()
 
 	utils.InitLogger(slog.Level(*lvl))
+	if err := errors.Join(envErrors...); err != nil {
+		slog.Error("invalid environment variables", slog.Any("err", err))
+		os.Exit(1)
+	}
 
-	rpcPluginImp := &plugin.RpcPlugin{}
+	rpcPluginImp := &plugin.RpcPlugin{
+		KubeConfigOptions: utils.KubeConfigOptions{
+			Kubeconfig:        *kubeconfig,
+			Context:           *kubeContext,
+			QPS:               float32(*qps),
+			Burst:             *burst,
+			UserAgent:         *userAgent,
+			Impersonate:       *impersonate,
+			ImpersonateGroups: splitList(*impersonateGroups),
+		},
+		FailOnMissingPermissions: *failOnMissingPermissions,
+		SweepOrphanRoutes:        *sweepOrphanRoutes,
+	}
+
+	if *configFile != "" {
+		global, err := plugin.LoadGlobalConfig(*configFile)
+		if err != nil {
+			slog.Error("unable to load the plugin configuration file", slog.String("path", *configFile), slog.Any("err", err))
+			os.Exit(1)
+		}
+		applyGlobalConfig(rpcPluginImp, nil, global)
+		previous := global
+		go plugin.WatchGlobalConfig(context.Background(), *configFile, configPollInterval, func(updated *plugin.GlobalConfig) {
+			if updated.MetricsAddress != global.MetricsAddress {
+				slog.Warn("the new metricsAddress is used when the plugin restarts", slog.String("metricsAddress", updated.MetricsAddress))
+			}
+			applyGlobalConfig(rpcPluginImp, previous, updated)
+			previous = updated
+		})
+		if global.MetricsAddress != "" {
+			go serveMetrics(global.MetricsAddress)
+		}
+	}
+
+	go utils.ToggleDebugOnSignal()
+	if *debugAddress != "" {
+		go serveDebug(*debugAddress, rpcPluginImp)
+	}
 
 	//  pluginMap is the map of plugins we can dispense.
 	var pluginMap = map[string]goPlugin.Plugin{
@@ -41,3 +128,84 @@ func main() {
 		Plugins:         pluginMap,
 	})
 }
+
+// applyGlobalConfig makes the plugin use the configuration file, along with its log level or the one given with -l.
+// The log level is only set when it changed from the previous file, nil at startup, so that reloading the file
+// keeps the level set through SIGUSR1 or /debug/loglevel.
+func applyGlobalConfig(r *plugin.RpcPlugin, previous, global *plugin.GlobalConfig) {
+	r.SetGlobalConfig(global)
+	if previous != nil && reflect.DeepEqual(previous.LogLevel, global.LogLevel) {
+		return
+	}
+	if global.LogLevel != nil {
+		utils.SetLogLevel(*global.LogLevel)
+	} else if previous != nil {
+		utils.SetLogLevel(slog.Level(*lvl))
+	}
+}
+
+// serveMetrics serves the Prometheus metrics of the plugin on /metrics
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", plugin.MetricsHandler())
+	slog.Info("serving metrics", slog.String("address", addr))
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("unable to serve metrics", slog.String("address", addr), slog.Any("err", err))
+	}
+}
+
+// serveDebug serves the debug endpoints of the plugin
+func serveDebug(addr string, r *plugin.RpcPlugin) {
+	slog.Info("serving debug endpoints", slog.String("address", addr))
+	if err := http.ListenAndServe(addr, r.DebugHandler()); err != nil {
+		slog.Error("unable to serve debug endpoints", slog.String("address", addr), slog.Any("err", err))
+	}
+}
+
+func envOrDefault(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+// envErrors holds the invalid values of environment variables, reported once the logger is set up
+var envErrors []error
+
+// envFloat returns the numeric value of an environment variable, or 0 if it is unset
+func envFloat(key string) float64 {
+	s := os.Getenv(key)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		envErrors = append(envErrors, fmt.Errorf("%s must be a number, got %q", key, s))
+		return 0
+	}
+	return v
+}
+
+// envInt returns the integer value of an environment variable, or 0 if it is unset
+func envInt(key string) int {
+	s := os.Getenv(key)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		envErrors = append(envErrors, fmt.Errorf("%s must be an integer, got %q", key, s))
+		return 0
+	}
+	return v
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"log/slog"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/utils"
+)
+
+// Command is a subcommand of the plugin binary
+type Command struct {
+	// Name is the first argument selecting the command
+	Name string
+	// Short is a one-line description shown in the usage
+	Short string
+	// Run executes the command with the remaining arguments
+	Run func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]Command{}
+
+func register(c Command) {
+	commands[c.Name] = c
+}
+
+// Lookup returns the subcommand with the given name.
+func Lookup(name string) (Command, bool) {
+	c, ok := commands[name]
+	return c, ok
+}
+
+// Usage describes the available subcommands.
+func Usage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Subcommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-10s %s\n", name, commands[name].Short)
+	}
+	return b.String()
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors instead of exiting.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// kubeFlags registers the flags selecting the cluster to reach, which default to the current kubeconfig context.
+func kubeFlags(fs *flag.FlagSet) *utils.KubeConfigOptions {
+	opts := &utils.KubeConfigOptions{UserAgent: "rollouts-plugin-trafficrouter-openshift"}
+	fs.StringVar(&opts.Kubeconfig, "kubeconfig", "", "path of the kubeconfig file, KUBECONFIG and ~/.kube/config are used when empty")
+	fs.StringVar(&opts.Context, "context", "", "the kubeconfig context to use")
+	fs.StringVar(&opts.Impersonate, "as", "", "user to impersonate")
+	return opts
+}
+
+// pluginNameFlags registers the repeatable flag giving the names the plugin is registered under besides
+// argoproj-labs/openshift, as in pluginNames of the plugin configuration file.
+func pluginNameFlags(fs *flag.FlagSet) *stringList {
+	names := &stringList{}
+	fs.Var(names, "plugin-name", "another name the plugin is registered under in the rollouts controller (repeatable)")
+	return names
+}
+
+// globalConfigFlags registers the flag giving the plugin configuration file, along with -plugin-name.
+// The returned function loads the file, adding the names given with -plugin-name to its pluginNames.
+func globalConfigFlags(fs *flag.FlagSet) func() (*plugin.GlobalConfig, error) {
+	path := fs.String("config", "", "path of the plugin configuration file, whose defaults and policies apply as in the plugin")
+	names := pluginNameFlags(fs)
+	return func() (*plugin.GlobalConfig, error) {
+		global := &plugin.GlobalConfig{}
+		if *path != "" {
+			var err error
+			if global, err = plugin.LoadGlobalConfig(*path); err != nil {
+				return nil, fmt.Errorf("%s: %w", *path, err)
+			}
+		}
+		for _, name := range *names {
+			if !slices.Contains(global.PluginNames, name) {
+				global.PluginNames = append(global.PluginNames, name)
+			}
+		}
+		return global, nil
+	}
+}
+
+// setLogger sends the logs of the plugin to stderr, only showing warnings and errors unless verbose.
+func setLogger(stderr io.Writer, verbose bool) {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level})))
+}
+
+// splitReference splits a <namespace>/<name> reference.
+func splitReference(flagName, ref string) (string, string, error) {
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("-%s must be of the form <namespace>/<name>, got %q", flagName, ref)
+	}
+	return namespace, name, nil
+}
+
+// stringList is a flag that may be repeated
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// ErrFailed is returned by commands that already reported their failure to the user.
+var ErrFailed = errors.New("command failed")
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// manifest is a single document read from a YAML or JSON file
+type manifest struct {
+	// File is the path of the file the document was read from
+	File string
+	// Index is the position of the document in the file
+	Index int
+	// JSON holds the document converted to JSON
+	JSON []byte
+	// Object is the document decoded as a generic Kubernetes object
+	Object *unstructured.Unstructured
+}
+
+// Location identifies the document for error messages.
+func (m manifest) Location() string {
+	return fmt.Sprintf("%s[%d]", m.File, m.Index)
+}
+
+// readManifests reads every document of the given files, skipping empty ones.
+func readManifests(files []string) ([]manifest, error) {
+	var manifests []manifest
+	for _, file := range files {
+		data, err := os.ReadFile(file) // #nosec G304 -- the files are given on the command line
+		if err != nil {
+			return nil, err
+		}
+		docs, err := splitDocuments(file, data)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, docs...)
+	}
+	return manifests, nil
+}
+
+func splitDocuments(file string, data []byte) ([]manifest, error) {
+	var manifests []manifest
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for index := 0; ; index++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return manifests, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", file, index, err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		js, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", file, index, err)
+		}
+		if bytes.Equal(bytes.TrimSpace(js), []byte("null")) {
+			continue
+		}
+
+		m := manifest{File: file, Index: index, JSON: js}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(js); err == nil {
+			m.Object = obj
+		}
+		manifests = append(manifests, m)
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+func init() {
+	register(Command{
+		Name:  "rbac",
+		Short: "print the least-privilege RBAC manifests for the given plugin configurations",
+		Run:   runRBAC,
+	})
+}
+
+// rbacLabels are set on the generated bindings, as in yaml/rbac.yaml
+var rbacLabels = map[string]string{
+	"app.kubernetes.io/component": "rollouts-controller",
+	"app.kubernetes.io/name":      "argo-rollouts",
+	"app.kubernetes.io/part-of":   "argo-rollouts",
+}
+
+func runRBAC(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rbac", stderr)
+	var files stringList
+	fs.Var(&files, "f", "a Rollout manifest, a plugin configuration block, or the manifest of a canary Service (repeatable)")
+	namespace := fs.String("namespace", "", "the namespace of the rollout, for plugin configuration blocks and Rollouts without one")
+	name := fs.String("name", "rollouts-plugin-trafficrouter-openshift", "the name of the generated roles and bindings")
+	serviceAccount := fs.String("service-account", "argo-rollouts", "the service account of the argo-rollouts controller")
+	serviceAccountNamespace := fs.String("service-account-namespace", "argo-rollouts", "the namespace of the argo-rollouts service account")
+	pluginNames := pluginNameFlags(fs)
+	clusterScope := fs.Bool("cluster-scope", false, "print a ClusterRole and ClusterRoleBinding instead of per-namespace Roles")
+	sweepOrphanRoutes := fs.Bool("sweep-orphan-routes", false, "also grant the cluster-wide access the plugin needs with -sweep-orphan-routes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("at least one file must be given with -f")
+	}
+
+	manifests, err := readManifests(files)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := servingCertSecrets(manifests, *namespace)
+	if err != nil {
+		return err
+	}
+
+	rules := map[string][]rbacv1.PolicyRule{}
+	for _, m := range manifests {
+		openshift, target, err := configFromManifest(m, *namespace, *pluginNames)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Location(), err)
+		}
+		if openshift == nil {
+			continue
+		}
+		target.ServingCertSecrets = secrets[target.CanaryService]
+		if err := plugin.AddPolicyRules(rules, openshift, target); err != nil {
+			return fmt.Errorf("%s: %w", m.Location(), err)
+		}
+		if _, ok := rules[""]; ok {
+			return fmt.Errorf("%s: the namespace of the rollout is unknown, set it with -namespace", m.Location())
+		}
+	}
+	if len(rules) == 0 {
+		return errors.New("no plugin configuration found in the given files")
+	}
+
+	subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: *serviceAccount, Namespace: *serviceAccountNamespace}
+	var objs []runtime.Object
+	if *clusterScope {
+		var all []rbacv1.PolicyRule
+		for _, ns := range sortedNamespaces(rules) {
+			all = plugin.MergePolicyRules(all, rules[ns]...)
+		}
+		if *sweepOrphanRoutes {
+			all = plugin.MergePolicyRules(all, plugin.SweeperPolicyRules()...)
+		}
+		objs = append(objs,
+			&rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: *name},
+				Rules:      all,
+			},
+			&rbacv1.ClusterRoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: *name, Labels: rbacLabels},
+				Subjects:   []rbacv1.Subject{subject},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: *name},
+			})
+	} else {
+		for _, ns := range sortedNamespaces(rules) {
+			objs = append(objs,
+				&rbacv1.Role{
+					TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+					ObjectMeta: metav1.ObjectMeta{Name: *name, Namespace: ns},
+					Rules:      rules[ns],
+				},
+				&rbacv1.RoleBinding{
+					TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+					ObjectMeta: metav1.ObjectMeta{Name: *name, Namespace: ns, Labels: rbacLabels},
+					Subjects:   []rbacv1.Subject{subject},
+					RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: *name},
+				})
+		}
+		if *sweepOrphanRoutes {
+			sweeper := *name + "-sweeper"
+			objs = append(objs,
+				&rbacv1.ClusterRole{
+					TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+					ObjectMeta: metav1.ObjectMeta{Name: sweeper},
+					Rules:      plugin.SweeperPolicyRules(),
+				},
+				&rbacv1.ClusterRoleBinding{
+					TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+					ObjectMeta: metav1.ObjectMeta{Name: sweeper, Labels: rbacLabels},
+					Subjects:   []rbacv1.Subject{subject},
+					RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: sweeper},
+				})
+		}
+	}
+	return printObjects(stdout, objs...)
+}
+
+// configFromManifest returns the plugin configuration held by a Rollout manifest
+// or a standalone plugin configuration block, along with the rollout it applies to.
+// It returns a nil configuration for other kinds of objects and for the rollouts that have no block
+// under one of the names of the plugin.
+func configFromManifest(m manifest, defaultNamespace string, pluginNames []string) (*plugin.OpenshiftTrafficRouting, plugin.PolicyTarget, error) {
+	if m.Object == nil {
+		openshift, err := plugin.ParseConfig(m.JSON)
+		return openshift, plugin.PolicyTarget{Namespace: defaultNamespace}, err
+	}
+	if m.Object.GetKind() != "Rollout" {
+		return nil, plugin.PolicyTarget{}, nil
+	}
+
+	var rollout v1alpha1.Rollout
+	if err := json.Unmarshal(m.JSON, &rollout); err != nil {
+		return nil, plugin.PolicyTarget{}, err
+	}
+	if !rolloutUsesPlugin(&rollout, pluginNames) {
+		return nil, plugin.PolicyTarget{}, nil
+	}
+	if rollout.Namespace == "" {
+		rollout.Namespace = defaultNamespace
+	}
+	target := plugin.PolicyTarget{Namespace: rollout.Namespace}
+	if canary := rollout.Spec.Strategy.Canary; canary != nil {
+		target.CanaryService = canary.CanaryService
+	}
+	openshift, err := plugin.ConfigFromRollout(&rollout, pluginNames...)
+	return openshift, target, err
+}
+
+// servingCertSecrets returns, for the name of each Service manifest, the namespaces of the Services
+// of that name mapped to the Secret holding their serving certificate.
+func servingCertSecrets(manifests []manifest, defaultNamespace string) (map[string]map[string]string, error) {
+	secrets := map[string]map[string]string{}
+	for _, m := range manifests {
+		if m.Object == nil || m.Object.GetKind() != "Service" || m.Object.GetAPIVersion() != "v1" {
+			continue
+		}
+		var service corev1.Service
+		if err := json.Unmarshal(m.JSON, &service); err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Location(), err)
+		}
+		if service.Namespace == "" {
+			service.Namespace = defaultNamespace
+		}
+		if secret := plugin.ServingCertSecretName(&service); secret != "" {
+			if secrets[service.Name] == nil {
+				secrets[service.Name] = map[string]string{}
+			}
+			secrets[service.Name][service.Namespace] = secret
+		}
+	}
+	return secrets, nil
+}
+
+// printObjects writes the objects as a multi-document YAML stream.
+func printObjects(w io.Writer, objs ...runtime.Object) error {
+	for i, obj := range objs {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(content, "status")
+
+		data, err := yaml.Marshal(content)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedNamespaces(rules map[string][]rbacv1.PolicyRule) []string {
+	namespaces := make([]string, 0, len(rules))
+	for ns := range rules {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
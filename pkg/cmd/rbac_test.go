@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const rbacRollout = `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: rollouts-demo
+  namespace: apps
+spec:
+  strategy:
+    canary:
+      canaryService: canary
+      stableService: stable
+      trafficRouting:
+        plugins:
+          argoproj-labs/openshift:
+            routes: [a, b, edge/c]
+`
+
+// writeFile writes content to a file in a temporary directory and returns its path
+func writeFile(name, content string) string {
+	path := filepath.Join(GinkgoT().TempDir(), name)
+	Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	return path
+}
+
+var _ = Describe("rbac command", func() {
+	var stdout, stderr *bytes.Buffer
+
+	BeforeEach(func() {
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	It("should print a Role and RoleBinding per route namespace", func() {
+		file := writeFile("rollout.yaml", rbacRollout)
+		Expect(runRBAC([]string{"-f", file}, stdout, stderr)).To(Succeed())
+
+		out := stdout.String()
+		Expect(regexp.MustCompile(`(?m)^kind: Role$`).FindAllString(out, -1)).To(HaveLen(2))
+		Expect(regexp.MustCompile(`(?m)^kind: RoleBinding$`).FindAllString(out, -1)).To(HaveLen(2))
+		Expect(out).To(ContainSubstring("namespace: apps"))
+		Expect(out).To(ContainSubstring("namespace: edge"))
+		Expect(out).To(ContainSubstring("resourceNames:\n  - a\n  - b\n"))
+		Expect(out).ToNot(ContainSubstring("'*'"))
+	})
+
+	It("should skip the rollouts configuring other plugins", func() {
+		other := strings.Replace(rbacRollout, "argoproj-labs/openshift:", "argoproj-labs/gatewayAPI:", 1)
+		other = strings.Replace(other, "edge/c", "other/c", 1)
+		file := writeFile("rollout.yaml", rbacRollout+"---\n"+other)
+		Expect(runRBAC([]string{"-f", file}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).ToNot(ContainSubstring("namespace: other"))
+	})
+
+	It("should print a ClusterRole when asked for cluster scope", func() {
+		file := writeFile("rollout.yaml", rbacRollout)
+		Expect(runRBAC([]string{"-f", file, "-cluster-scope"}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("kind: ClusterRole\n"))
+		Expect(stdout.String()).To(ContainSubstring("kind: ClusterRoleBinding\n"))
+	})
+
+	It("should merge the rules of every namespace in the ClusterRole", func() {
+		web := strings.Replace(rbacRollout, "namespace: apps", "namespace: web", 1)
+		edge := strings.Replace(rbacRollout, "namespace: apps", "namespace: edge", 1)
+		edge = strings.Replace(edge, "routes: [a, b, edge/c]", "routes: [c, a]", 1)
+		file := writeFile("rollout.yaml", rbacRollout+"---\n"+web+"---\n"+edge)
+		Expect(runRBAC([]string{"-f", file, "-cluster-scope", "-sweep-orphan-routes"}, stdout, stderr)).To(Succeed())
+
+		out := stdout.String()
+		Expect(strings.Count(out, "  - routes\n")).To(Equal(2))
+		Expect(out).To(ContainSubstring("resourceNames:\n  - a\n  - b\n  - c\n  resources:\n  - routes\n  verbs:\n  - get\n  - update\n"))
+		Expect(out).To(ContainSubstring("  - routes\n  verbs:\n  - list\n  - delete\n"))
+	})
+
+	It("should accept a plugin configuration block with a namespace", func() {
+		file := writeFile("config.yaml", "routes: [a]\n")
+		Expect(runRBAC([]string{"-f", file}, stdout, stderr)).To(MatchError(ContainSubstring("-namespace")))
+
+		Expect(runRBAC([]string{"-f", file, "-namespace", "apps"}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("namespace: apps"))
+	})
+
+	It("should allow creating and deleting the preview route", func() {
+		file := writeFile("config.yaml", "routes: [a]\npreviewRoute: {}\n")
+		Expect(runRBAC([]string{"-f", file, "-namespace", "apps"}, stdout, stderr)).To(Succeed())
+
+		out := stdout.String()
+		Expect(out).To(ContainSubstring("resourceNames:\n  - a\n  - a-preview\n"))
+		Expect(out).To(ContainSubstring("  - routes/custom-host\n  verbs:\n  - create\n"))
+		Expect(out).To(ContainSubstring("  - a-preview\n  resources:\n  - routes\n  verbs:\n  - delete\n"))
+	})
+
+	It("should reject an invalid plugin configuration", func() {
+		file := writeFile("config.yaml", "routes: [a/b/c]\n")
+		err := runRBAC([]string{"-f", file, "-namespace", "apps"}, stdout, stderr)
+		Expect(err).To(MatchError(ContainSubstring("OpenshiftInvalidConfig")))
+		Expect(err).To(MatchError(ContainSubstring("config.yaml[0]")))
+	})
+
+	It("should restrict the canary certificate check to the canary Service and its Secret", func() {
+		rollout := strings.Replace(rbacRollout, "routes: [a, b, edge/c]", "routes: [a]\n            verifyCanaryCertificate: true", 1)
+		file := writeFile("rollout.yaml", rollout)
+		Expect(runRBAC([]string{"-f", file}, stdout, stderr)).To(MatchError(ContainSubstring("manifest of the Service")))
+
+		service := writeFile("service.yaml", `apiVersion: v1
+kind: Service
+metadata:
+  name: canary
+  namespace: apps
+  annotations:
+    service.beta.openshift.io/serving-cert-secret-name: canary-tls
+`)
+		Expect(runRBAC([]string{"-f", file, "-f", service}, stdout, stderr)).To(Succeed())
+		out := stdout.String()
+		Expect(out).To(ContainSubstring("resourceNames:\n  - canary\n  resources:\n  - services\n"))
+		Expect(out).To(ContainSubstring("resourceNames:\n  - canary-tls\n  resources:\n  - secrets\n"))
+	})
+
+	It("should grant the cluster-wide access of the orphan sweeper when asked", func() {
+		file := writeFile("rollout.yaml", rbacRollout)
+		Expect(runRBAC([]string{"-f", file, "-sweep-orphan-routes"}, stdout, stderr)).To(Succeed())
+		out := stdout.String()
+		Expect(out).To(ContainSubstring("kind: ClusterRole\nmetadata:\n  name: rollouts-plugin-trafficrouter-openshift-sweeper\n"))
+		Expect(out).To(ContainSubstring("  - rollouts\n  verbs:\n  - get\n"))
+	})
+})
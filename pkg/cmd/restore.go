@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+)
+
+func init() {
+	register(Command{
+		Name:  "restore",
+		Short: "send the traffic of managed routes back to the stable service or to their state before the canary",
+		Run:   runRestore,
+	})
+}
+
+// stdin answers the confirmation prompt, replaced in tests
+var stdin io.Reader = os.Stdin
+
+func runRestore(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("restore", stderr)
+	rolloutRef := fs.String("rollout", "", "restore the routes managed for the rollout, as <namespace>/<name>")
+	routeRef := fs.String("route", "", "restore only this route, as <namespace>/<name>")
+	to := fs.String("to", string(plugin.RestoreStable), "the state to restore: stable sends all traffic to the stable service, "+
+		"snapshot puts back the backends recorded before the canary")
+	dryRun := fs.Bool("dry-run", false, "only print the changes")
+	yes := fs.Bool("yes", false, "apply the changes without asking for confirmation")
+	verbose := fs.Bool("v", false, "show the logs of the plugin")
+	loadGlobalConfig := globalConfigFlags(fs)
+	opts := kubeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *rolloutRef == "" && *routeRef == "" {
+		return errors.New("the routes must be selected with -rollout, -route or both")
+	}
+	mode := plugin.RestoreMode(*to)
+	if mode != plugin.RestoreStable && mode != plugin.RestoreSnapshot {
+		return fmt.Errorf("-to must be %s or %s, got %q", plugin.RestoreStable, plugin.RestoreSnapshot, *to)
+	}
+	if *routeRef != "" {
+		if _, _, err := splitReference("route", *routeRef); err != nil {
+			return err
+		}
+	}
+	setLogger(stderr, *verbose)
+	global, err := loadGlobalConfig()
+	if err != nil {
+		return err
+	}
+
+	r, err := newPlugin(*opts)
+	if err != nil {
+		return err
+	}
+	r.SetGlobalConfig(global)
+	ctx := context.Background()
+	var rollout *v1alpha1.Rollout
+	if *rolloutRef != "" {
+		namespace, name, err := splitReference("rollout", *rolloutRef)
+		if err != nil {
+			return err
+		}
+		if rollout, err = r.GetRollout(ctx, namespace, name); err != nil {
+			return err
+		}
+	}
+
+	plans, err := r.PlanRestore(ctx, rollout, *routeRef, mode)
+	if err != nil {
+		return err
+	}
+	changes, failed := 0, false
+	for _, plan := range plans {
+		switch {
+		case plan.Err != nil:
+			failed = true
+			fmt.Fprintf(stdout, "route %s cannot be restored: %v\n", plan.Route, plan.Err)
+		case !plan.Changed():
+			fmt.Fprintf(stdout, "route %s is already restored\n", plan.Route)
+		default:
+			changes++
+			current, err := routeContent(plan.Current)
+			if err != nil {
+				return err
+			}
+			restored, err := routeContent(plan.Restored)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "route %s (-current +restored):\n", plan.Route)
+			if err := printDiff(stdout, current, restored); err != nil {
+				return err
+			}
+		}
+	}
+
+	if changes > 0 && !*dryRun {
+		if !*yes && !confirm(stdout, fmt.Sprintf("Restore %d route(s)?", changes)) {
+			return errors.New("aborted, no route was changed")
+		}
+		if err := r.ApplyRestore(ctx, plans); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%d route(s) restored\n", changes)
+	}
+	if failed {
+		return ErrFailed
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on stdout and reads the answer from stdin.
+func confirm(stdout io.Writer, question string) bool {
+	fmt.Fprintf(stdout, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
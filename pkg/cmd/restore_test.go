@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore command", func() {
+	var stdout, stderr *bytes.Buffer
+
+	BeforeEach(func() {
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	answer := func(text string) {
+		original := stdin
+		DeferCleanup(func() { stdin = original })
+		stdin = strings.NewReader(text)
+	}
+
+	It("should print the changes without applying them in a dry run", func() {
+		useFakeCluster(statusRollout(20, "a"), splitRoute("a", 80, 20))
+		Expect(runRestore([]string{"-rollout", "apps/rollouts-demo", "-dry-run"}, stdout, stderr)).To(Succeed())
+
+		out := stdout.String()
+		Expect(out).To(ContainSubstring("route apps/a (-current +restored):\n"))
+		Expect(out).To(ContainSubstring("-     weight: 80\n"))
+		Expect(out).To(ContainSubstring("+     weight: 100\n"))
+		Expect(out).To(ContainSubstring("-   alternateBackends:\n"))
+		Expect(out).ToNot(ContainSubstring("restored\n"))
+	})
+
+	It("should apply the changes once confirmed", func() {
+		useFakeCluster(statusRollout(20, "a", "b"), splitRoute("a", 80, 20), splitRoute("b", 100, 0))
+		answer("y\n")
+		Expect(runRestore([]string{"-rollout", "apps/rollouts-demo"}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("route apps/b is already restored\n"))
+		Expect(stdout.String()).To(ContainSubstring("Restore 1 route(s)? [y/N] 1 route(s) restored\n"))
+
+		stdout.Reset()
+		Expect(runStatus([]string{"-rollout", "apps/rollouts-demo"}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(MatchRegexp(`Route: apps/a\n(.*\n){3}Service/stable\s+100\s+100.0%\n\n`))
+	})
+
+	It("should not apply the changes unless confirmed", func() {
+		useFakeCluster(statusRollout(20, "a"), splitRoute("a", 80, 20))
+		answer("\n")
+		err := runRestore([]string{"-rollout", "apps/rollouts-demo", "-route", "apps/a"}, stdout, stderr)
+		Expect(err).To(MatchError(ContainSubstring("aborted")))
+	})
+
+	It("should report routes that cannot be restored", func() {
+		useFakeCluster(statusRollout(20, "a"), splitRoute("a", 80, 20))
+		err := runRestore([]string{"-route", "apps/a", "-to", "snapshot", "-yes"}, stdout, stderr)
+		Expect(err).To(MatchError(ErrFailed))
+		Expect(stdout.String()).To(ContainSubstring("route apps/a cannot be restored: OpenshiftInvalidConfig: route apps/a has no recorded snapshot"))
+	})
+
+	It("should validate its flags", func() {
+		Expect(runRestore(nil, stdout, stderr)).To(MatchError(ContainSubstring("-rollout, -route or both")))
+		Expect(runRestore([]string{"-route", "a"}, stdout, stderr)).To(MatchError(ContainSubstring("<namespace>/<name>")))
+		Expect(runRestore([]string{"-route", "apps/a", "-to", "canary"}, stdout, stderr)).To(MatchError(ContainSubstring("-to must be")))
+	})
+})
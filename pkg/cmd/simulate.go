@@ -0,0 +1,375 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	routev1 "github.com/openshift/api/route/v1"
+	routeclientset "github.com/openshift/client-go/route/clientset/versioned"
+	routefake "github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+func init() {
+	register(Command{
+		Name:  "simulate",
+		Short: "replay the canary steps of a rollout against routes read from files, without a cluster",
+		Run:   runSimulate,
+	})
+}
+
+// pod template hashes of the simulated revisions
+const (
+	simulatedStableHash = "stable"
+	simulatedCanaryHash = "canary"
+)
+
+func runSimulate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("simulate", stderr)
+	var files stringList
+	fs.Var(&files, "f", "a file holding the Rollout and the Routes, Services and Secrets it uses (repeatable)")
+	namespace := fs.String("namespace", "default", "the namespace of objects without one")
+	verbose := fs.Bool("v", false, "show the logs of the plugin")
+	loadGlobalConfig := globalConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("at least one file must be given with -f")
+	}
+	setLogger(stderr, *verbose)
+	global, err := loadGlobalConfig()
+	if err != nil {
+		return err
+	}
+
+	manifests, err := readManifests(files)
+	if err != nil {
+		return err
+	}
+	rollout, routes, objects, err := simulationObjects(manifests, *namespace, global.PluginNames)
+	if err != nil {
+		return err
+	}
+	if err := dropClusterRoutes(rollout, global.PluginNames, stdout); err != nil {
+		return err
+	}
+
+	routeClient := routefake.NewSimpleClientset(routes...)
+	r := plugin.NewForClients(routeClient, fake.NewSimpleClientset(objects...), rolloutfake.NewSimpleClientset(rollout))
+	r.SetGlobalConfig(global)
+	rollout.Status.StableRS = simulatedStableHash
+	rollout.Status.CurrentPodHash = simulatedCanaryHash
+	r.UpdateHash(rollout, simulatedCanaryHash, simulatedStableHash, nil)
+
+	s := &simulation{stdout: stdout, routeClient: routeClient}
+	if _, err := s.snapshot(); err != nil {
+		return err
+	}
+
+	weight := int32(0)
+	steps := rollout.Spec.Strategy.Canary.Steps
+	for i, step := range steps {
+		title := fmt.Sprintf("step %d", i+1)
+		rollout.Status.CurrentStepIndex = ptr.To(int32(i))
+		var rpcErr pluginTypes.RpcError
+		switch {
+		case step.SetWeight != nil:
+			weight = *step.SetWeight
+			rpcErr = r.SetWeight(rollout, weight, nil)
+			title += fmt.Sprintf(": setWeight %d", weight)
+			if !rpcErr.HasError() {
+				recordWeight(rollout, weight)
+			}
+		case step.Experiment != nil:
+			var destinations []v1alpha1.WeightDestination
+			for _, template := range step.Experiment.Templates {
+				if template.Weight != nil {
+					destinations = append(destinations, v1alpha1.WeightDestination{ServiceName: template.Name, Weight: *template.Weight})
+				}
+			}
+			rpcErr = r.SetWeight(rollout, weight, destinations)
+			title += fmt.Sprintf(": experiment at weight %d", weight)
+			if len(destinations) > 0 {
+				title += ", the weights of its templates are not supported by the plugin and send them no traffic"
+			}
+		case step.SetHeaderRoute != nil:
+			title += ": setHeaderRoute is not supported by the plugin and leaves the routes unchanged"
+		case step.SetMirrorRoute != nil:
+			title += ": setMirrorRoute is not supported by the plugin and leaves the routes unchanged"
+		case step.Pause != nil:
+			title += ": pause"
+		case step.Analysis != nil:
+			title += ": analysis"
+		case step.SetCanaryScale != nil:
+			title += ": setCanaryScale"
+		}
+		if err := s.report(title, rpcErr); err != nil {
+			return err
+		}
+	}
+
+	// the canary receives all the traffic, then becomes the stable revision and the routes go back to the stable service
+	rollout.Status.CurrentStepIndex = ptr.To(int32(len(steps)))
+	if err := s.report("promotion: setWeight 100", r.SetWeight(rollout, 100, nil)); err != nil {
+		return err
+	}
+	rollout.Status.StableRS = simulatedCanaryHash
+	r.UpdateHash(rollout, simulatedCanaryHash, simulatedCanaryHash, nil)
+	return s.report("promoted: setWeight 0", r.SetWeight(rollout, 0, nil))
+}
+
+// recordWeight records the weight in the status of the rollout, as the controller does once SetWeight succeeds.
+func recordWeight(rollout *v1alpha1.Rollout, weight int32) {
+	rollout.Status.Canary.Weights = &v1alpha1.TrafficWeights{
+		Canary: v1alpha1.WeightDestination{Weight: weight, ServiceName: rollout.Spec.Strategy.Canary.CanaryService},
+		Stable: v1alpha1.WeightDestination{Weight: 100 - weight, ServiceName: rollout.Spec.Strategy.Canary.StableService},
+	}
+}
+
+// simulationObjects returns the rollout, routes and other objects of the manifests.
+func simulationObjects(manifests []manifest, namespace string, pluginNames []string) (*v1alpha1.Rollout, []runtime.Object, []runtime.Object, error) {
+	var rollout *v1alpha1.Rollout
+	var routes, objects []runtime.Object
+	for _, m := range manifests {
+		if m.Object == nil {
+			return nil, nil, nil, fmt.Errorf("%s: not a Kubernetes object", m.Location())
+		}
+		var obj interface {
+			runtime.Object
+			GetNamespace() string
+			SetNamespace(string)
+		}
+		switch m.Object.GetKind() {
+		case "Rollout":
+			var candidate v1alpha1.Rollout
+			if err := json.Unmarshal(m.JSON, &candidate); err != nil {
+				return nil, nil, nil, fmt.Errorf("%s: %w", m.Location(), err)
+			}
+			if !rolloutUsesPlugin(&candidate, pluginNames) {
+				continue
+			}
+			if rollout != nil {
+				return nil, nil, nil, fmt.Errorf("%s: only one Rollout can be simulated at a time", m.Location())
+			}
+			rollout = &candidate
+			obj = rollout
+		case "Route":
+			obj = &routev1.Route{}
+		case "Service":
+			obj = &corev1.Service{}
+		case "Secret":
+			obj = &corev1.Secret{}
+		default:
+			continue
+		}
+		if err := json.Unmarshal(m.JSON, obj); err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", m.Location(), err)
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		switch obj.(type) {
+		case *routev1.Route:
+			routes = append(routes, obj)
+		case *v1alpha1.Rollout:
+		default:
+			objects = append(objects, obj)
+		}
+	}
+	if rollout == nil {
+		return nil, nil, nil, errors.New("no Rollout using the plugin found in the given files")
+	}
+	if _, err := plugin.ConfigFromRollout(rollout, pluginNames...); err != nil {
+		return nil, nil, nil, err
+	}
+	return rollout, routes, objects, nil
+}
+
+// dropClusterRoutes removes the routes of remote clusters from the plugin configuration,
+// since reaching them would need their kubeconfig.
+func dropClusterRoutes(rollout *v1alpha1.Rollout, pluginNames []string, stdout io.Writer) error {
+	openshift, err := plugin.ConfigFromRollout(rollout, pluginNames...)
+	if err != nil {
+		return err
+	}
+	if len(openshift.ClusterRoutes) == 0 {
+		return nil
+	}
+	if len(openshift.Routes) == 0 {
+		return errors.New("the rollout only has routes in remote clusters, which are not simulated")
+	}
+	fmt.Fprintln(stdout, "Routes in remote clusters are not simulated.")
+	openshift.ClusterRoutes = nil
+	config, err := json.Marshal(openshift)
+	if err != nil {
+		return err
+	}
+	plugins := rollout.Spec.Strategy.Canary.TrafficRouting.Plugins
+	key, _ := pluginKey(plugins, pluginNames)
+	plugins[key] = config
+	return nil
+}
+
+// simulation prints the changes the plugin makes to the routes
+type simulation struct {
+	stdout      io.Writer
+	routeClient routeclientset.Interface
+	// previous holds the routes, as <namespace>/<name>, after the last step
+	previous map[string]map[string]any
+}
+
+// snapshot records the current state of the routes and returns the previous one.
+func (s *simulation) snapshot() (map[string]map[string]any, error) {
+	routes, err := s.routeClient.RouteV1().Routes(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]map[string]any{}
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		content, err := routeContent(route)
+		if err != nil {
+			return nil, err
+		}
+		current[route.Namespace+"/"+route.Name] = content
+	}
+	previous := s.previous
+	s.previous = current
+	return previous, nil
+}
+
+// report prints the result of a step along with the changes it made to the routes.
+func (s *simulation) report(title string, rpcErr pluginTypes.RpcError) error {
+	fmt.Fprintf(s.stdout, "=== %s\n", title)
+	previous, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for name := range previous {
+		names[name] = true
+	}
+	for name := range s.previous {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	changed := false
+	for _, name := range sorted {
+		before, after := previous[name], s.previous[name]
+		if equality.Semantic.DeepEqual(before, after) {
+			continue
+		}
+		changed = true
+		if after == nil {
+			fmt.Fprintf(s.stdout, "route %s deleted\n", name)
+			continue
+		}
+		if before == nil {
+			fmt.Fprintf(s.stdout, "route %s created:\n", name)
+		} else {
+			fmt.Fprintf(s.stdout, "route %s (-before +after):\n", name)
+		}
+		if err := printDiff(s.stdout, before, after); err != nil {
+			return err
+		}
+	}
+	if !changed {
+		fmt.Fprintln(s.stdout, "no route changes")
+	}
+
+	if rpcErr.HasError() {
+		fmt.Fprintf(s.stdout, "Error: %s\n", rpcErr.Error())
+		return ErrFailed
+	}
+	return nil
+}
+
+// routeContent returns the fields of the route worth comparing.
+func routeContent(route *routev1.Route) (map[string]any, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(route)
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(content, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(content, "metadata", "managedFields")
+	unstructured.RemoveNestedField(content, "metadata", "uid")
+	unstructured.RemoveNestedField(content, "metadata", "generation")
+	unstructured.RemoveNestedField(content, "status")
+	return content, nil
+}
+
+// printDiff writes the YAML of after, marking the lines removed from or added to the YAML of before.
+func printDiff(w io.Writer, before, after map[string]any) error {
+	var beforeLines []string
+	if before != nil {
+		data, err := yaml.Marshal(before)
+		if err != nil {
+			return err
+		}
+		beforeLines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	data, err := yaml.Marshal(after)
+	if err != nil {
+		return err
+	}
+	afterLines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of beforeLines[i:] and afterLines[j:]
+	lcs := make([][]int, len(beforeLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(afterLines)+1)
+	}
+	for i := len(beforeLines) - 1; i >= 0; i-- {
+		for j := len(afterLines) - 1; j >= 0; j-- {
+			if beforeLines[i] == afterLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(beforeLines) || j < len(afterLines) {
+		switch {
+		case i < len(beforeLines) && j < len(afterLines) && beforeLines[i] == afterLines[j]:
+			b.WriteString("  " + beforeLines[i] + "\n")
+			i++
+			j++
+		case i < len(beforeLines) && (j == len(afterLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			b.WriteString("- " + beforeLines[i] + "\n")
+			i++
+		default:
+			b.WriteString("+ " + afterLines[j] + "\n")
+			j++
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
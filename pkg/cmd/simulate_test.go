@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const simulateRollout = `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: rollouts-demo
+  namespace: apps
+spec:
+  strategy:
+    canary:
+      canaryService: canary
+      stableService: stable
+      steps:
+        - setWeight: 20
+        - pause: {}
+        - setHeaderRoute:
+            name: header
+        - setWeight: 50
+      trafficRouting:
+        plugins:
+          argoproj-labs/openshift:
+            routes: [rollouts-demo]
+            previewRoute: {}
+`
+
+const simulateRoutes = `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: rollouts-demo
+  namespace: apps
+spec:
+  host: demo.example.com
+  to:
+    kind: Service
+    name: stable
+`
+
+var _ = Describe("simulate command", func() {
+	var stdout, stderr *bytes.Buffer
+
+	BeforeEach(func() {
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	It("should print the route changes of every step", func() {
+		rollout := writeFile("rollout.yaml", simulateRollout)
+		routes := writeFile("routes.yaml", simulateRoutes)
+		Expect(runSimulate([]string{"-f", rollout, "-f", routes}, stdout, stderr)).To(Succeed())
+
+		out := stdout.String()
+		Expect(out).To(ContainSubstring("=== step 1: setWeight 20\nroute apps/rollouts-demo (-before +after):\n"))
+		Expect(out).To(ContainSubstring("route apps/rollouts-demo-preview created:\n"))
+		Expect(out).To(ContainSubstring("=== step 2: pause\nno route changes\n"))
+		Expect(out).To(ContainSubstring("=== step 3: setHeaderRoute is not supported by the plugin and leaves the routes unchanged\nno route changes\n"))
+		Expect(out).To(ContainSubstring("=== step 4: setWeight 50\nroute apps/rollouts-demo (-before +after):\n"))
+		Expect(out).To(ContainSubstring("=== promotion: setWeight 100\n"))
+		Expect(out).To(ContainSubstring("=== promoted: setWeight 0\nroute apps/rollouts-demo (-before +after):\n"))
+		Expect(out).To(ContainSubstring("route apps/rollouts-demo-preview deleted\n"))
+		Expect(out).To(ContainSubstring("    to:\n      kind: Service\n      name: stable\n-     weight: 80\n+     weight: 50\n"))
+		Expect(out).To(ContainSubstring("+   alternateBackends:\n+   - kind: Service\n+     name: canary\n+     weight: 20\n"))
+	})
+
+	It("should stop at the first failing step", func() {
+		rollout := writeFile("rollout.yaml", simulateRollout)
+		err := runSimulate([]string{"-f", rollout}, stdout, stderr)
+		Expect(err).To(MatchError(ErrFailed))
+		Expect(stdout.String()).To(ContainSubstring("=== step 1: setWeight 20\nno route changes\nError: OpenshiftRouteNotFound"))
+		Expect(stdout.String()).ToNot(ContainSubstring("step 2"))
+	})
+
+	It("should stop at a step exceeding the weight limits", func() {
+		limited := strings.Replace(simulateRollout, "            previewRoute: {}\n", "            maxWeightIncrease: 25\n", 1)
+		rollout := writeFile("rollout.yaml", limited)
+		routes := writeFile("routes.yaml", simulateRoutes)
+		Expect(runSimulate([]string{"-f", rollout, "-f", routes}, stdout, stderr)).To(MatchError(ErrFailed))
+		Expect(stdout.String()).To(ContainSubstring("=== step 4: setWeight 50\nno route changes\nError: OpenshiftWeightLimitExceeded: " +
+			"weight 50 of rollout apps/rollouts-demo increases the canary weight by 30 from 20, more than maxWeightIncrease 25"))
+	})
+
+	It("should report the weights of experiment templates as unsupported", func() {
+		experiment := strings.Replace(simulateRollout, "        - pause: {}\n", `        - experiment:
+            templates:
+              - name: baseline
+                specRef: stable
+                weight: 10
+`, 1)
+		rollout := writeFile("rollout.yaml", experiment)
+		routes := writeFile("routes.yaml", simulateRoutes)
+		Expect(runSimulate([]string{"-f", rollout, "-f", routes}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("=== step 2: experiment at weight 20, the weights of its templates are not supported by the plugin " +
+			"and send them no traffic\nno route changes\n"))
+	})
+
+	It("should require a Rollout", func() {
+		routes := writeFile("routes.yaml", simulateRoutes)
+		Expect(runSimulate([]string{"-f", routes}, stdout, stderr)).To(MatchError("no Rollout using the plugin found in the given files"))
+	})
+
+	It("should skip the rollouts configuring other plugins", func() {
+		other := strings.Replace(simulateRollout, "argoproj-labs/openshift:", "argoproj-labs/gatewayAPI:", 1)
+		other = strings.Replace(other, "name: rollouts-demo\n", "name: other\n", 1)
+		rollouts := writeFile("rollouts.yaml", other+"---\n"+simulateRollout)
+		routes := writeFile("routes.yaml", simulateRoutes)
+		Expect(runSimulate([]string{"-f", rollouts, "-f", routes}, stdout, stderr)).To(Succeed())
+
+		only := writeFile("other.yaml", other)
+		Expect(runSimulate([]string{"-f", only, "-f", routes}, stdout, stderr)).To(MatchError("no Rollout using the plugin found in the given files"))
+	})
+})
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/utils"
+)
+
+func init() {
+	register(Command{
+		Name:  "status",
+		Short: "print the live traffic split of the routes managed for a rollout",
+		Run:   runStatus,
+	})
+}
+
+// newPlugin builds the plugin reaching the cluster, replaced in tests
+var newPlugin = func(opts utils.KubeConfigOptions) (*plugin.RpcPlugin, error) {
+	cfg, err := utils.NewKubeConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.NewForConfig(cfg)
+}
+
+func runStatus(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("status", stderr)
+	rolloutRef := fs.String("rollout", "", "the rollout, as <namespace>/<name>")
+	verbose := fs.Bool("v", false, "show the logs of the plugin")
+	loadGlobalConfig := globalConfigFlags(fs)
+	opts := kubeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *rolloutRef == "" {
+		return errors.New("the rollout must be given with -rollout")
+	}
+	namespace, name, err := splitReference("rollout", *rolloutRef)
+	if err != nil {
+		return err
+	}
+	setLogger(stderr, *verbose)
+	global, err := loadGlobalConfig()
+	if err != nil {
+		return err
+	}
+
+	r, err := newPlugin(*opts)
+	if err != nil {
+		return err
+	}
+	r.SetGlobalConfig(global)
+	ctx := context.Background()
+	rollout, err := r.GetRollout(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	statuses, err := r.Status(ctx, rollout)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for i, status := range statuses {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		if status.Err != nil {
+			failed = true
+		}
+		if err := printRouteStatus(stdout, status); err != nil {
+			return err
+		}
+	}
+	if failed {
+		return ErrFailed
+	}
+	return nil
+}
+
+// printRouteStatus writes the backends, admissions and drift of a route as tables.
+func printRouteStatus(w io.Writer, status plugin.RouteStatus) error {
+	fmt.Fprintf(w, "Route: %s\n", status.Route)
+	if status.Err != nil {
+		fmt.Fprintf(w, "Error: %v\n", status.Err)
+		return nil
+	}
+	if status.Host != "" {
+		fmt.Fprintf(w, "Host:  %s\n", status.Host)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nBACKEND\tWEIGHT\tTRAFFIC")
+	for _, backend := range status.Backends {
+		kind := backend.Kind
+		if kind == "" {
+			kind = "Service"
+		}
+		fmt.Fprintf(tw, "%s/%s\t%d\t%.1f%%\n", kind, backend.Name, backend.Weight, backend.Percent)
+	}
+
+	if len(status.Admissions) > 0 {
+		fmt.Fprintln(tw, "\nROUTER\tADMITTED\tREASON\tMESSAGE")
+		for _, admission := range status.Admissions {
+			admitted := string(admission.Status)
+			if !admission.Reported {
+				admitted = "NotReported"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", admission.Router, admitted, dash(admission.Reason), dash(admission.Message))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(status.Drift) == 0 {
+		_, err := fmt.Fprintln(w, "\nDrift: none")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nDrift:\n  - %s\n", strings.Join(status.Drift, "\n  - "))
+	return err
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
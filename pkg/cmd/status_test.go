@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/utils"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	routefake "github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// statusRollout returns a rollout managing the given routes of the apps namespace at the given canary weight
+func statusRollout(canaryWeight int32, routes ...string) *v1alpha1.Rollout {
+	config, err := json.Marshal(plugin.OpenshiftTrafficRouting{Routes: routes, RouterShards: []string{"default", "internal"}})
+	Expect(err).ToNot(HaveOccurred())
+	return &v1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{Name: "rollouts-demo", Namespace: "apps"},
+		Spec: v1alpha1.RolloutSpec{Strategy: v1alpha1.RolloutStrategy{Canary: &v1alpha1.CanaryStrategy{
+			StableService: "stable",
+			CanaryService: "canary",
+			TrafficRouting: &v1alpha1.RolloutTrafficRouting{
+				Plugins: map[string]json.RawMessage{plugin.PluginName: config},
+			},
+		}}},
+		Status: v1alpha1.RolloutStatus{Canary: v1alpha1.CanaryStatus{Weights: &v1alpha1.TrafficWeights{
+			Canary: v1alpha1.WeightDestination{Weight: canaryWeight},
+			Stable: v1alpha1.WeightDestination{Weight: 100 - canaryWeight},
+		}}},
+	}
+}
+
+// splitRoute returns a route of the apps namespace sending the given weights to the stable and canary services
+func splitRoute(name string, stableWeight, canaryWeight int32) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec: routev1.RouteSpec{
+			Host:              name + ".example.com",
+			To:                routev1.RouteTargetReference{Kind: "Service", Name: "stable", Weight: &stableWeight},
+			AlternateBackends: []routev1.RouteTargetReference{{Kind: "Service", Name: "canary", Weight: &canaryWeight}},
+		},
+		Status: routev1.RouteStatus{Ingress: []routev1.RouteIngress{{
+			RouterName: "default",
+			Conditions: []routev1.RouteIngressCondition{{Type: routev1.RouteAdmitted, Status: corev1.ConditionTrue}},
+		}}},
+	}
+}
+
+// useFakeCluster makes the commands reach the same fake clients holding the given objects
+func useFakeCluster(rollout *v1alpha1.Rollout, routes ...runtime.Object) {
+	original := newPlugin
+	DeferCleanup(func() { newPlugin = original })
+	r := plugin.NewForClients(routefake.NewSimpleClientset(routes...), k8sfake.NewSimpleClientset(), rolloutfake.NewSimpleClientset(rollout))
+	newPlugin = func(utils.KubeConfigOptions) (*plugin.RpcPlugin, error) {
+		return r, nil
+	}
+}
+
+var _ = Describe("status command", func() {
+	var stdout, stderr *bytes.Buffer
+
+	BeforeEach(func() {
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	It("should print the traffic split, admission and drift of every route", func() {
+		useFakeCluster(statusRollout(20, "a", "b"), splitRoute("a", 80, 20), splitRoute("b", 70, 30))
+		Expect(runStatus([]string{"-rollout", "apps/rollouts-demo"}, stdout, stderr)).To(Succeed())
+
+		out := stdout.String()
+		Expect(out).To(ContainSubstring("Route: apps/a\nHost:  a.example.com\n"))
+		Expect(out).To(MatchRegexp(`Service/stable\s+80\s+80.0%`))
+		Expect(out).To(MatchRegexp(`Service/canary\s+20\s+20.0%`))
+		Expect(out).To(MatchRegexp(`default\s+True\s+-\s+-`))
+		Expect(out).To(MatchRegexp(`internal\s+NotReported`))
+		Expect(out).To(ContainSubstring("Route: apps/b\n"))
+		Expect(out).To(ContainSubstring("the route sends 70 to the stable service and 30 to the canary, the rollout set 80 and 20"))
+		Expect(stderr.String()).To(BeEmpty())
+	})
+
+	It("should apply the plugin configuration file", func() {
+		useFakeCluster(statusRollout(20, "a"), splitRoute("a", 205, 51))
+		config := writeFile("config.yaml", "defaults:\n  weightScale: 256\n")
+		Expect(runStatus([]string{"-rollout", "apps/rollouts-demo", "-config", config}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(MatchRegexp(`Service/stable\s+205\s+80.1%`))
+		Expect(stdout.String()).To(ContainSubstring("Drift: none"))
+
+		invalid := writeFile("invalid.yaml", "defaults:\n  weightScale: 1000\n")
+		Expect(runStatus([]string{"-rollout", "apps/rollouts-demo", "-config", invalid}, stdout, stderr)).To(MatchError(ContainSubstring("defaults.weightScale")))
+	})
+
+	It("should report routes that cannot be read", func() {
+		useFakeCluster(statusRollout(0, "a", "missing"), splitRoute("a", 100, 0))
+		err := runStatus([]string{"-rollout", "apps/rollouts-demo"}, stdout, stderr)
+		Expect(err).To(MatchError(ErrFailed))
+		Expect(stdout.String()).To(ContainSubstring("Route: apps/missing\nError: OpenshiftRouteNotFound"))
+		Expect(stdout.String()).To(ContainSubstring("Drift: none"))
+	})
+
+	It("should require a rollout reference", func() {
+		Expect(runStatus(nil, stdout, stderr)).To(MatchError(ContainSubstring("-rollout")))
+		Expect(runStatus([]string{"-rollout", "rollouts-demo"}, stdout, stderr)).To(MatchError(ContainSubstring("<namespace>/<name>")))
+	})
+
+	It("should leave a list of kubeconfig files in KUBECONFIG to the default loading rules", func() {
+		dir := GinkgoT().TempDir()
+		cluster := filepath.Join(dir, "cluster")
+		Expect(os.WriteFile(cluster, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: edge
+  cluster:
+    server: https://edge.example.com:6443
+contexts:
+- name: edge
+  context:
+    cluster: edge
+    user: admin
+current-context: edge
+`), 0o600)).To(Succeed())
+		user := filepath.Join(dir, "user")
+		Expect(os.WriteFile(user, []byte(`apiVersion: v1
+kind: Config
+users:
+- name: admin
+  user:
+    token: secret
+`), 0o600)).To(Succeed())
+		GinkgoT().Setenv("KUBECONFIG", cluster+string(filepath.ListSeparator)+user)
+
+		original := newPlugin
+		DeferCleanup(func() { newPlugin = original })
+		var config *rest.Config
+		newPlugin = func(opts utils.KubeConfigOptions) (*plugin.RpcPlugin, error) {
+			Expect(opts.Kubeconfig).To(BeEmpty())
+			var err error
+			config, err = utils.NewKubeConfig(opts)
+			Expect(err).ToNot(HaveOccurred())
+			return nil, errors.New("unreachable")
+		}
+		Expect(runStatus([]string{"-rollout", "apps/rollouts-demo"}, stdout, stderr)).To(MatchError(ContainSubstring("unreachable")))
+		Expect(config.Host).To(Equal("https://edge.example.com:6443"))
+		Expect(config.BearerToken).To(Equal("secret"))
+	})
+
+	It("should report a missing rollout", func() {
+		useFakeCluster(&v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "apps"}})
+		err := runStatus([]string{"-rollout", "apps/rollouts-demo"}, stdout, stderr)
+		Expect(err).To(MatchError(ContainSubstring(`rollout "rollouts-demo" not found in namespace "apps"`)))
+	})
+})
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/plugin"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	register(Command{
+		Name:  "validate",
+		Short: "check the plugin configuration of Rollouts, and the Routes and Services they use, offline",
+		Run:   runValidate,
+	})
+}
+
+// located is an object read from a manifest
+type located[T any] struct {
+	Object   T
+	Location string
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", stderr)
+	var files stringList
+	fs.Var(&files, "f", "a file holding Rollouts, Routes and Services (repeatable)")
+	namespace := fs.String("namespace", "default", "the namespace of objects without one")
+	pluginNames := pluginNameFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("at least one file must be given with -f")
+	}
+
+	manifests, err := readManifests(files)
+	if err != nil {
+		return err
+	}
+
+	var rollouts []located[*v1alpha1.Rollout]
+	routes := map[string]located[*routev1.Route]{}
+	services := map[string]*corev1.Service{}
+	problems := 0
+	report := func(location string, errs field.ErrorList) {
+		for _, err := range errs {
+			fmt.Fprintf(stdout, "%s: %s\n", location, err.Error())
+			problems++
+		}
+	}
+
+	for _, m := range manifests {
+		if m.Object == nil {
+			continue
+		}
+		switch m.Object.GetKind() {
+		case "Rollout":
+			rollout := &v1alpha1.Rollout{}
+			if err := json.Unmarshal(m.JSON, rollout); err != nil {
+				report(m.Location(), field.ErrorList{field.Invalid(field.NewPath("spec"), nil, err.Error())})
+				continue
+			}
+			if rollout.Namespace == "" {
+				rollout.Namespace = *namespace
+			}
+			rollouts = append(rollouts, located[*v1alpha1.Rollout]{rollout, m.Location()})
+		case "Route":
+			route := &routev1.Route{}
+			if err := json.Unmarshal(m.JSON, route); err != nil {
+				report(m.Location(), field.ErrorList{field.Invalid(field.NewPath("spec"), nil, err.Error())})
+				continue
+			}
+			if route.Namespace == "" {
+				route.Namespace = *namespace
+			}
+			routes[route.Namespace+"/"+route.Name] = located[*routev1.Route]{route, m.Location()}
+		case "Service":
+			service := &corev1.Service{}
+			if err := json.Unmarshal(m.JSON, service); err != nil {
+				report(m.Location(), field.ErrorList{field.Invalid(field.NewPath("spec"), nil, err.Error())})
+				continue
+			}
+			if service.Namespace == "" {
+				service.Namespace = *namespace
+			}
+			services[service.Namespace+"/"+service.Name] = service
+		}
+	}
+
+	checked := 0
+	for _, rollout := range rollouts {
+		if !rolloutUsesPlugin(rollout.Object, *pluginNames) {
+			continue
+		}
+		canary := rollout.Object.Spec.Strategy.Canary
+		checked++
+		errs := plugin.ValidateRollout(rollout.Object, *pluginNames...)
+		report(rollout.Location, errs)
+		if len(errs) > 0 {
+			continue
+		}
+
+		openshift, err := plugin.ConfigFromRollout(rollout.Object, *pluginNames...)
+		if err != nil {
+			return err
+		}
+		namespaces := map[string]bool{}
+		for _, ref := range openshift.Routes {
+			namespace, name, found := strings.Cut(ref, "/")
+			if !found {
+				namespace, name = rollout.Object.Namespace, ref
+			}
+			// once Services are given, the routes of every namespace need the stable and canary Services among them
+			if len(services) > 0 && !namespaces[namespace] {
+				namespaces[namespace] = true
+				report(rollout.Location, missingServices(services, namespace, canary))
+			}
+			route, ok := routes[namespace+"/"+name]
+			if !ok {
+				continue
+			}
+			stable := services[namespace+"/"+canary.StableService]
+			canaryService := services[namespace+"/"+canary.CanaryService]
+			report(route.Location, validateRouteServices(route.Object, canary.StableService, stable, canaryService))
+		}
+	}
+	if checked == 0 {
+		return errors.New("no Rollout using the plugin found in the given files")
+	}
+
+	if problems > 0 {
+		fmt.Fprintf(stdout, "%d problem(s) found\n", problems)
+		return ErrFailed
+	}
+	fmt.Fprintf(stdout, "%d rollout(s) valid\n", checked)
+	return nil
+}
+
+// missingServices reports the stable and canary services of the rollout that are not among the services
+// of the namespace of its routes.
+func missingServices(services map[string]*corev1.Service, namespace string, canary *v1alpha1.CanaryStrategy) field.ErrorList {
+	var errs field.ErrorList
+	canaryPath := field.NewPath("spec", "strategy", "canary")
+	for _, service := range []struct{ field, name string }{
+		{"stableService", canary.StableService},
+		{"canaryService", canary.CanaryService},
+	} {
+		if _, ok := services[namespace+"/"+service.name]; !ok {
+			errs = append(errs, field.NotFound(canaryPath.Child(service.field), namespace+"/"+service.name))
+		}
+	}
+	return errs
+}
+
+// validateRouteServices checks that the route sends its traffic to the stable service and that its target port
+// exists on the stable and canary services, when they are known.
+func validateRouteServices(route *routev1.Route, stableName string, services ...*corev1.Service) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	if route.Spec.To.Name != stableName {
+		errs = append(errs, field.Invalid(specPath.Child("to", "name"), route.Spec.To.Name, fmt.Sprintf("must be the stable service %q of the rollout", stableName)))
+	}
+	if route.Spec.Port == nil {
+		return errs
+	}
+
+	targetPort := route.Spec.Port.TargetPort
+	for _, service := range services {
+		if service != nil && !servicePortMatches(service, targetPort) {
+			errs = append(errs, field.Invalid(specPath.Child("port", "targetPort"), targetPort.String(),
+				fmt.Sprintf("service %q has no port named or targeting it", service.Name)))
+		}
+	}
+	return errs
+}
+
+// servicePortMatches tells whether the route target port, a port name or a container port number, is exposed by the service.
+func servicePortMatches(service *corev1.Service, targetPort intstr.IntOrString) bool {
+	for _, port := range service.Spec.Ports {
+		if targetPort.Type == intstr.String {
+			if port.Name == targetPort.StrVal {
+				return true
+			}
+			continue
+		}
+		if port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal == targetPort.IntVal {
+			return true
+		}
+		if port.TargetPort.IntVal == 0 && port.TargetPort.StrVal == "" && port.Port == targetPort.IntVal {
+			return true
+		}
+	}
+	return false
+}
+
+// rolloutUsesPlugin tells whether the rollout configures the plugin under one of its names.
+func rolloutUsesPlugin(rollout *v1alpha1.Rollout, pluginNames []string) bool {
+	canary := rollout.Spec.Strategy.Canary
+	return canary != nil && canary.TrafficRouting != nil && usesPlugin(canary.TrafficRouting.Plugins, pluginNames)
+}
+
+// usesPlugin tells whether the plugins map has a block under one of the names of the plugin.
+// Unlike the plugin, the subcommands do not take the only block of a rollout for its own, since the
+// files may hold rollouts using other plugins.
+func usesPlugin(plugins map[string]json.RawMessage, pluginNames []string) bool {
+	_, ok := pluginKey(plugins, pluginNames)
+	return ok
+}
+
+// pluginKey returns the key of the block of the plugin in the plugins map: PluginName or the first
+// of the other names of the plugin that the map holds.
+func pluginKey(plugins map[string]json.RawMessage, pluginNames []string) (string, bool) {
+	for _, name := range append([]string{plugin.PluginName}, pluginNames...) {
+		if _, ok := plugins[name]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
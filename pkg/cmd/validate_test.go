@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const validateServices = `apiVersion: v1
+kind: Service
+metadata:
+  name: stable
+  namespace: apps
+spec:
+  ports:
+    - name: http
+      port: 80
+      targetPort: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: canary
+  namespace: apps
+spec:
+  ports:
+    - name: web
+      port: 80
+      targetPort: 8080
+`
+
+// validateRollout is the simulated rollout without its unsupported header route step
+var validateRollout = strings.Replace(simulateRollout, "        - setHeaderRoute:\n            name: header\n", "", 1)
+
+var _ = Describe("validate command", func() {
+	var stdout, stderr *bytes.Buffer
+
+	BeforeEach(func() {
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	It("should accept a valid rollout along with its routes and services", func() {
+		rollout := writeFile("rollout.yaml", validateRollout)
+		routes := writeFile("routes.yaml", simulateRoutes+"  port:\n    targetPort: 8080\n")
+		services := writeFile("services.yaml", validateServices)
+		Expect(runValidate([]string{"-f", rollout, "-f", routes, "-f", services}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(Equal("1 rollout(s) valid\n"))
+	})
+
+	It("should report every problem with its file and field", func() {
+		rollout := writeFile("rollout.yaml", `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: rollouts-demo
+  namespace: apps
+spec:
+  strategy:
+    canary:
+      stableService: stable
+      steps:
+        - setWeight: 20
+        - setMirrorRoute:
+            name: mirror
+      trafficRouting:
+        plugins:
+          argoproj-labs/openshift:
+            routes: [rollouts-demo, a/b/c]
+            balance: roundrobin
+`)
+		err := runValidate([]string{"-f", rollout}, stdout, stderr)
+		Expect(err).To(MatchError(ErrFailed))
+
+		out := stdout.String()
+		prefix := rollout + "[0]: spec.strategy.canary."
+		Expect(out).To(ContainSubstring(prefix + "trafficRouting.plugins[argoproj-labs/openshift].balance: Unsupported value: \"balance\""))
+		Expect(out).To(ContainSubstring(prefix + "trafficRouting.plugins[argoproj-labs/openshift].routes[1]: Invalid value: \"a/b/c\""))
+		Expect(out).To(ContainSubstring(prefix + "canaryService: Required value"))
+		Expect(out).To(ContainSubstring(prefix + "steps[1].setMirrorRoute: Forbidden: traffic mirroring is not supported by the plugin"))
+		Expect(out).To(HaveSuffix("4 problem(s) found\n"))
+	})
+
+	It("should report routes whose port or backend does not match the services", func() {
+		rollout := writeFile("rollout.yaml", validateRollout)
+		routes := writeFile("routes.yaml", simulateRoutes+"  port:\n    targetPort: http\n")
+		services := writeFile("services.yaml", validateServices)
+		Expect(runValidate([]string{"-f", rollout, "-f", routes, "-f", services}, stdout, stderr)).To(MatchError(ErrFailed))
+		Expect(stdout.String()).To(Equal(routes + `[0]: spec.port.targetPort: Invalid value: "http": service "canary" has no port named or targeting it` + "\n1 problem(s) found\n"))
+
+		routes = writeFile("other.yaml", "apiVersion: route.openshift.io/v1\nkind: Route\nmetadata:\n  name: rollouts-demo\n  namespace: apps\nspec:\n  to:\n    name: legacy\n")
+		stdout.Reset()
+		Expect(runValidate([]string{"-f", rollout, "-f", routes}, stdout, stderr)).To(MatchError(ErrFailed))
+		Expect(stdout.String()).To(ContainSubstring(`spec.to.name: Invalid value: "legacy": must be the stable service "stable" of the rollout`))
+	})
+
+	It("should report the stable and canary services missing from the given services", func() {
+		rollout := writeFile("rollout.yaml", validateRollout)
+		routes := writeFile("routes.yaml", simulateRoutes+"  port:\n    targetPort: 8080\n")
+		services := writeFile("services.yaml", strings.SplitN(validateServices, "---\n", 2)[0])
+		Expect(runValidate([]string{"-f", rollout, "-f", routes, "-f", services}, stdout, stderr)).To(MatchError(ErrFailed))
+		Expect(stdout.String()).To(Equal(rollout + `[0]: spec.strategy.canary.canaryService: Not found: "apps/canary"` + "\n1 problem(s) found\n"))
+
+		// without any service given, the services are not checked
+		stdout.Reset()
+		Expect(runValidate([]string{"-f", rollout, "-f", routes}, stdout, stderr)).To(Succeed())
+	})
+
+	It("should fail when no rollout uses the plugin", func() {
+		services := writeFile("services.yaml", validateServices)
+		Expect(runValidate([]string{"-f", services}, stdout, stderr)).To(MatchError(ContainSubstring("no Rollout using the plugin")))
+	})
+
+	It("should only check rollouts configuring the plugin under one of its names", func() {
+		renamed := strings.Replace(validateRollout, "argoproj-labs/openshift:", "mirror/openshift:", 1)
+		rollout := writeFile("rollout.yaml", renamed)
+		routes := writeFile("routes.yaml", simulateRoutes+"  port:\n    targetPort: 8080\n")
+		services := writeFile("services.yaml", validateServices)
+		Expect(runValidate([]string{"-f", rollout, "-f", routes, "-f", services}, stdout, stderr)).To(MatchError(ContainSubstring("no Rollout using the plugin")))
+
+		stdout.Reset()
+		Expect(runValidate([]string{"-plugin-name", "mirror/openshift", "-f", rollout, "-f", routes, "-f", services}, stdout, stderr)).To(Succeed())
+		Expect(stdout.String()).To(Equal("1 rollout(s) valid\n"))
+	})
+})
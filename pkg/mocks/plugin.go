@@ -63,10 +63,14 @@ func newRoute(name string) *routev1.Route {
 			Host: "http://route.example.com",
 			Port: &routev1.RoutePort{TargetPort: intstr.FromInt(8080)},
 			To: routev1.RouteTargetReference{
+				Kind:   "Service",
+				Name:   StableServiceName,
 				Weight: desiredWeight,
 			},
 			AlternateBackends: []routev1.RouteTargetReference{
 				{
+					Kind:   "Service",
+					Name:   CanaryServiceName,
 					Weight: desiredAltWeight,
 				},
 			},
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ShardAdmission is the admission state of a route by one router shard
+type ShardAdmission struct {
+	// Router is the name of the router shard
+	Router string
+	// Reported is false for a required shard that did not report any status for the route
+	Reported bool
+	// Status of the Admitted condition: True, False or Unknown
+	Status  corev1.ConditionStatus
+	Reason  string
+	Message string
+}
+
+// Admitted tells whether the router shard admitted the route
+func (a ShardAdmission) Admitted() bool {
+	return a.Status == corev1.ConditionTrue
+}
+
+// admissionStatus returns the admission state of the route by the given router shards,
+// or by every shard reporting a status when none are given.
+func admissionStatus(route *routev1.Route, shards []string) []ShardAdmission {
+	var admissions []ShardAdmission
+	reported := map[string]bool{}
+	for _, ingress := range route.Status.Ingress {
+		if len(shards) > 0 && !slices.Contains(shards, ingress.RouterName) {
+			continue
+		}
+		reported[ingress.RouterName] = true
+
+		admission := ShardAdmission{Router: ingress.RouterName, Reported: true, Status: corev1.ConditionUnknown}
+		for _, condition := range ingress.Conditions {
+			if condition.Type == routev1.RouteAdmitted {
+				admission.Status = condition.Status
+				admission.Reason = condition.Reason
+				admission.Message = condition.Message
+			}
+		}
+		admissions = append(admissions, admission)
+	}
+
+	for _, shard := range shards {
+		if !reported[shard] {
+			admissions = append(admissions, ShardAdmission{Router: shard, Status: corev1.ConditionUnknown})
+		}
+	}
+	return admissions
+}
+
+// rejectionError returns a RouterRejected error if one of the given router shards,
+// or any shard when none are given, refused to admit the route.
+func rejectionError(route *routev1.Route, shards []string) error {
+	var rejections []string
+	for _, admission := range admissionStatus(route, shards) {
+		if admission.Status != corev1.ConditionFalse {
+			continue
+		}
+		rejection := fmt.Sprintf("router %q: %s", admission.Router, admission.Reason)
+		if admission.Message != "" {
+			rejection += ": " + admission.Message
+		}
+		rejections = append(rejections, rejection)
+	}
+	if len(rejections) == 0 {
+		return nil
+	}
+	return newError(ReasonRouterRejected, nil, "route %s/%s was rejected by %s", route.Namespace, route.Name, strings.Join(rejections, "; "))
+}
+
+func validateRouterShards(shards []string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, shard := range shards {
+		if shard == "" {
+			errs = append(errs, field.Required(path.Index(i), "the router name must not be empty"))
+		} else if seen[shard] {
+			errs = append(errs, field.Duplicate(path.Index(i), shard))
+		}
+		seen[shard] = true
+	}
+	return errs
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// routeIngress returns the status of a route reported by a router shard
+func routeIngress(router string, status corev1.ConditionStatus, reason string) routev1.RouteIngress {
+	return routev1.RouteIngress{
+		RouterName: router,
+		Conditions: []routev1.RouteIngressCondition{{
+			Type:   routev1.RouteAdmitted,
+			Status: status,
+			Reason: reason,
+		}},
+	}
+}
+
+var _ = Describe("Test router shard admission", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	setIngress := func(ingress ...routev1.RouteIngress) {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		route.Status.Ingress = ingress
+		_, err = routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+	})
+
+	It("should report the admission of every shard, or of the required ones only", func() {
+		route := &routev1.Route{Status: routev1.RouteStatus{Ingress: []routev1.RouteIngress{
+			routeIngress("default", corev1.ConditionTrue, ""),
+			routeIngress("internal", corev1.ConditionFalse, "HostAlreadyClaimed"),
+			{RouterName: "pending"},
+		}}}
+
+		admissions := admissionStatus(route, nil)
+		Expect(admissions).To(HaveLen(3))
+		Expect(admissions[0].Admitted()).To(BeTrue())
+		Expect(admissions[1].Reason).To(Equal("HostAlreadyClaimed"))
+		Expect(admissions[2].Status).To(Equal(corev1.ConditionUnknown))
+
+		admissions = admissionStatus(route, []string{"default", "edge"})
+		Expect(admissions).To(HaveLen(2))
+		Expect(admissions[0].Router).To(Equal("default"))
+		Expect(admissions[0].Admitted()).To(BeTrue())
+		Expect(admissions[1].Router).To(Equal("edge"))
+		Expect(admissions[1].Reported).To(BeFalse())
+	})
+
+	It("should only verify the weight once every reporting shard admitted the route", func() {
+		setIngress(routeIngress("default", corev1.ConditionTrue, ""), routev1.RouteIngress{RouterName: "internal"})
+		verified, rpcErr := r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.NotVerified))
+
+		setIngress(routeIngress("default", corev1.ConditionTrue, ""), routeIngress("internal", corev1.ConditionTrue, ""))
+		verified, rpcErr = r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+	})
+
+	It("should ignore shards that are not required", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:       []string{mocks.RouteName},
+			RouterShards: []string{"default"},
+		})
+		setIngress(routeIngress("default", corev1.ConditionTrue, ""), routeIngress("internal", corev1.ConditionUnknown, ""))
+		verified, rpcErr := r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+	})
+
+	It("should wait for a required shard that did not report yet", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:       []string{mocks.RouteName},
+			RouterShards: []string{"default", "edge"},
+		})
+		setIngress(routeIngress("default", corev1.ConditionTrue, ""))
+		verified, rpcErr := r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.NotVerified))
+	})
+
+	It("should leave the status of the routers to VerifyWeight", func() {
+		rejected := routeIngress("default", corev1.ConditionFalse, "HostAlreadyClaimed")
+		rejected.Conditions[0].Message = "route r already exposes www.example.com"
+		setIngress(rejected)
+
+		Expect(r.SetWeight(rollout, 50, nil).HasError()).To(BeFalse())
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*route.Spec.To.Weight).To(Equal(int32(50)))
+
+		verified, rpcErr := r.VerifyWeight(rollout, 50, nil)
+		Expect(verified).To(Equal(pluginTypes.NotVerified))
+		Expect(rpcErr.Error()).To(HavePrefix(string(ReasonRouterRejected) +
+			`: route default/argo-rollouts was rejected by router "default": HostAlreadyClaimed: route r already exposes www.example.com`))
+	})
+
+	It("should never hold back going back to the stable service", func() {
+		Expect(r.SetWeight(rollout, 50, nil).HasError()).To(BeFalse())
+		setIngress(routeIngress("default", corev1.ConditionFalse, "HostAlreadyClaimed"))
+
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		verified, rpcErr := r.VerifyWeight(rollout, 0, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+	})
+
+	It("should fail VerifyWeight when a required shard rejects the route", func() {
+		setIngress(routeIngress("default", corev1.ConditionTrue, ""), routeIngress("internal", corev1.ConditionFalse, "ExtendedValidationFailed"))
+		verified, rpcErr := r.VerifyWeight(rollout, 30, nil)
+		Expect(verified).To(Equal(pluginTypes.NotVerified))
+		Expect(rpcErr.Error()).To(HavePrefix(string(ReasonRouterRejected)))
+		Expect(rpcErr.Error()).To(ContainSubstring(`router "internal": ExtendedValidationFailed`))
+
+		// a rejection by a shard that is not required is ignored
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:       []string{mocks.RouteName},
+			RouterShards: []string{"default"},
+		})
+		verified, rpcErr = r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+	})
+})
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// TrafficMode selects how the plugin shifts traffic to the canary
+type TrafficMode string
+
+const (
+	// ModeWeighted shifts every request according to the weights
+	ModeWeighted TrafficMode = "weighted"
+	// ModeAffinity keeps sticky sessions and manages the router cookie per canary revision
+	ModeAffinity TrafficMode = "affinity"
+)
+
+// cookieNameAnnotation sets the name of the cookie the router uses for session affinity
+const cookieNameAnnotation = "router.openshift.io/cookie_name"
+
+const defaultCookiePrefix = "canary"
+
+// Affinity configures the affinity mode
+type Affinity struct {
+	// CookiePrefix is the prefix of the router cookie name, followed by the canary pod template hash
+	CookiePrefix string `json:"cookiePrefix,omitempty"`
+}
+
+// cookieName returns the name of the router cookie for a canary revision
+func (a *Affinity) cookieName(canaryHash string) string {
+	prefix := defaultCookiePrefix
+	if a != nil && a.CookiePrefix != "" {
+		prefix = a.CookiePrefix
+	}
+	return prefix + "-" + canaryHash
+}
+
+func validateAffinity(openshift *OpenshiftTrafficRouting, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	modes := []string{string(ModeWeighted), string(ModeAffinity)}
+	if openshift.Mode != "" && !slices.Contains(modes, string(openshift.Mode)) {
+		errs = append(errs, field.NotSupported(path.Child("mode"), openshift.Mode, modes))
+	}
+	if openshift.Mode != ModeAffinity {
+		if openshift.Affinity != nil {
+			errs = append(errs, field.Forbidden(path.Child("affinity"), "only allowed in the affinity mode"))
+		}
+		return errs
+	}
+
+	if lb := openshift.LoadBalancing; lb != nil && lb.DisableCookies != nil && *lb.DisableCookies {
+		errs = append(errs, field.Invalid(path.Child("loadBalancing", "disableCookies"), true, "cookies cannot be disabled in the affinity mode"))
+	}
+	if openshift.Affinity != nil && openshift.Affinity.CookiePrefix != "" && !cookieToken.MatchString(openshift.Affinity.CookiePrefix) {
+		errs = append(errs, field.Invalid(path.Child("affinity", "cookiePrefix"), openshift.Affinity.CookiePrefix, "must only contain letters, digits, '-', '_' and '.'"))
+	}
+	return errs
+}
+
+// canaryHashes remembers the canary pod template hash given to UpdateHash for each rollout
+type canaryHashes struct {
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+func (h *canaryHashes) set(rollout *v1alpha1.Rollout, hash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hash == "" {
+		delete(h.hashes, rolloutKey(rollout))
+		return
+	}
+	if h.hashes == nil {
+		h.hashes = map[string]string{}
+	}
+	h.hashes[rolloutKey(rollout)] = hash
+}
+
+// get returns the canary hash of the rollout, falling back to the hash of its current pod template
+// when UpdateHash was not called since the plugin started.
+func (h *canaryHashes) get(rollout *v1alpha1.Rollout) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hash, ok := h.hashes[rolloutKey(rollout)]; ok {
+		return hash
+	}
+	return rollout.Status.CurrentPodHash
+}
+
+func rolloutKey(rollout *v1alpha1.Rollout) string {
+	return rollout.Namespace + "/" + rollout.Name
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Test the affinity mode", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	getRoute := func() *routev1.Route {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return route
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:   []string{mocks.RouteName},
+			Mode:     ModeAffinity,
+			Affinity: &Affinity{CookiePrefix: "demo"},
+		})
+	})
+
+	It("should name the router cookie after the canary revision", func() {
+		Expect(r.UpdateHash(rollout, "abc", "def", nil).HasError()).To(BeFalse())
+		Expect(r.SetWeight(rollout, 20, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations).To(HaveKeyWithValue(cookieNameAnnotation, "demo-abc"))
+
+		verified, rpcErr := r.VerifyWeight(rollout, 20, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+
+		// a new canary revision gets a new cookie, so that existing sessions are balanced again
+		Expect(r.UpdateHash(rollout, "xyz", "def", nil).HasError()).To(BeFalse())
+		Expect(r.SetWeight(rollout, 40, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations).To(HaveKeyWithValue(cookieNameAnnotation, "demo-xyz"))
+
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations).ToNot(HaveKey(cookieNameAnnotation))
+	})
+
+	It("should keep the cookie while the rollout still sends traffic to the canary", func() {
+		rollout.Status.Canary.Weights = &v1alpha1.TrafficWeights{Canary: v1alpha1.WeightDestination{Weight: 20}}
+		Expect(r.UpdateHash(rollout, "abc", "def", nil).HasError()).To(BeFalse())
+		for range 3 {
+			Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+			Expect(r.SetWeight(rollout, 20, nil).HasError()).To(BeFalse())
+			Expect(getRoute().Annotations).To(HaveKeyWithValue(cookieNameAnnotation, "demo-abc"))
+		}
+		updates := 0
+		for _, action := range routeClient.Actions() {
+			if action.GetVerb() == "update" {
+				updates++
+			}
+		}
+		Expect(updates).To(Equal(1))
+	})
+
+	It("should fall back to the current pod hash of the rollout", func() {
+		rollout.Status.CurrentPodHash = "status-hash"
+		Expect(r.SetWeight(rollout, 20, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations).To(HaveKeyWithValue(cookieNameAnnotation, "demo-status-hash"))
+	})
+
+	It("should not manage the cookie in the weighted mode", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}})
+		Expect(r.UpdateHash(rollout, "abc", "def", nil).HasError()).To(BeFalse())
+		Expect(r.SetWeight(rollout, 20, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations).ToNot(HaveKey(cookieNameAnnotation))
+	})
+
+	It("should reject disabled cookies in the affinity mode", func() {
+		disable := true
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:        []string{mocks.RouteName},
+			Mode:          ModeAffinity,
+			LoadBalancing: &LoadBalancing{DisableCookies: &disable},
+		})
+		rpcErr := r.SetWeight(rollout, 20, nil)
+		Expect(rpcErr.Error()).To(ContainSubstring("loadBalancing.disableCookies: Invalid value: true"))
+	})
+})
@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// annotationPrefix is the prefix of the annotations the plugin sets on the objects it manages
+const annotationPrefix = "trafficrouter-openshift.argoproj-labs.io/"
+
+// originalAnnotationsKey holds, as JSON, the values the canary annotations had before the plugin
+// changed them, with null for annotations that were not set
+const originalAnnotationsKey = annotationPrefix + "original-annotations"
+
+// HAProxy router annotations managed through LoadBalancing
+const (
+	balanceAnnotation        = "haproxy.router.openshift.io/balance"
+	disableCookiesAnnotation = "haproxy.router.openshift.io/disable_cookies"
+	timeoutAnnotation        = "haproxy.router.openshift.io/timeout"
+)
+
+var (
+	balanceAlgorithms = []string{"roundrobin", "leastconn", "source", "random"}
+	haproxyTimeout    = regexp.MustCompile(`^[0-9]+(us|ms|s|m|h|d)?$`)
+	cookieToken       = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// LoadBalancing configures the router annotations applied to the routes while the canary receives traffic
+type LoadBalancing struct {
+	// Profile is the name of an annotation profile of the plugin configuration file the other settings are added to
+	Profile string `json:"profile,omitempty"`
+	// Balance is the HAProxy balance algorithm, e.g. roundrobin, which weights require to be effective
+	Balance string `json:"balance,omitempty"`
+	// DisableCookies disables cookie based stickiness, which pins clients to a backend
+	DisableCookies *bool `json:"disableCookies,omitempty"`
+	// Timeout is the HAProxy server timeout, e.g. 30s
+	Timeout string `json:"timeout,omitempty"`
+	// Annotations are additional annotations applied to the routes
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// annotations returns every annotation to apply to the routes during the canary.
+func (lb *LoadBalancing) annotations() map[string]string {
+	if lb == nil {
+		return nil
+	}
+	annotations := map[string]string{}
+	for k, v := range lb.Annotations {
+		annotations[k] = v
+	}
+	if lb.Balance != "" {
+		annotations[balanceAnnotation] = lb.Balance
+	}
+	if lb.DisableCookies != nil {
+		annotations[disableCookiesAnnotation] = "false"
+		if *lb.DisableCookies {
+			annotations[disableCookiesAnnotation] = "true"
+		}
+	}
+	if lb.Timeout != "" {
+		annotations[timeoutAnnotation] = lb.Timeout
+	}
+	return annotations
+}
+
+func validateLoadBalancing(lb *LoadBalancing, path *field.Path) field.ErrorList {
+	if lb == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if lb.Balance != "" && !slices.Contains(balanceAlgorithms, lb.Balance) {
+		errs = append(errs, field.NotSupported(path.Child("balance"), lb.Balance, balanceAlgorithms))
+	}
+	if lb.Profile != "" {
+		for _, msg := range validation.IsDNS1123Label(lb.Profile) {
+			errs = append(errs, field.Invalid(path.Child("profile"), lb.Profile, msg))
+		}
+	}
+	if lb.Timeout != "" && !haproxyTimeout.MatchString(lb.Timeout) {
+		errs = append(errs, field.Invalid(path.Child("timeout"), lb.Timeout, "must be a number followed by an optional unit: us, ms, s, m, h or d"))
+	}
+	for key := range lb.Annotations {
+		if key == originalAnnotationsKey {
+			errs = append(errs, field.Forbidden(path.Child("annotations").Key(key), "the annotation is reserved for the plugin"))
+		}
+	}
+	return errs
+}
+
+// applyCanaryAnnotations sets the canary annotations on the route, recording the values they replace
+// so that restoreAnnotations can put them back.
+func applyCanaryAnnotations(route *routev1.Route, annotations map[string]string) error {
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	originals, err := originalAnnotations(route)
+	if err != nil {
+		return err
+	}
+	if originals == nil {
+		originals = map[string]*string{}
+	}
+	if route.Annotations == nil {
+		route.Annotations = map[string]string{}
+	}
+
+	for key, value := range annotations {
+		if _, recorded := originals[key]; !recorded {
+			if current, ok := route.Annotations[key]; ok {
+				originals[key] = &current
+			} else {
+				originals[key] = nil
+			}
+		}
+		route.Annotations[key] = value
+	}
+
+	encoded, err := json.Marshal(originals)
+	if err != nil {
+		return err
+	}
+	route.Annotations[originalAnnotationsKey] = string(encoded)
+	return nil
+}
+
+// restoreAnnotations puts back the annotations recorded by applyCanaryAnnotations.
+func restoreAnnotations(route *routev1.Route) error {
+	originals, err := originalAnnotations(route)
+	if err != nil || originals == nil {
+		return err
+	}
+
+	for key, value := range originals {
+		if value == nil {
+			delete(route.Annotations, key)
+		} else {
+			route.Annotations[key] = *value
+		}
+	}
+	delete(route.Annotations, originalAnnotationsKey)
+	return nil
+}
+
+// originalAnnotations returns the annotation values recorded on the route, or nil if there are none.
+func originalAnnotations(route *routev1.Route) (map[string]*string, error) {
+	encoded, ok := route.Annotations[originalAnnotationsKey]
+	if !ok {
+		return nil, nil
+	}
+	var originals map[string]*string
+	if err := json.Unmarshal([]byte(encoded), &originals); err != nil {
+		return nil, newError(ReasonInvalidConfig, err, "invalid %s annotation on route %s/%s", originalAnnotationsKey, route.Namespace, route.Name)
+	}
+	return originals, nil
+}
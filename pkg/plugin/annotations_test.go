@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setPluginConfig replaces the plugin configuration of the rollout
+func setPluginConfig(rollout *v1alpha1.Rollout, openshift OpenshiftTrafficRouting) {
+	encoded, err := json.Marshal(openshift)
+	Expect(err).ToNot(HaveOccurred())
+	rollout.Spec.Strategy.Canary.TrafficRouting.Plugins[PluginName] = encoded
+}
+
+var _ = Describe("Test canary load-balancing annotations", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	getRoute := func() *routev1.Route {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return route
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+
+		route := getRoute()
+		route.Annotations = map[string]string{
+			balanceAnnotation:   "source",
+			"example.com/other": "kept",
+		}
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		disable := true
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes: []string{mocks.RouteName},
+			LoadBalancing: &LoadBalancing{
+				Balance:        "roundrobin",
+				DisableCookies: &disable,
+				Timeout:        "30s",
+			},
+		})
+	})
+
+	It("should apply the annotations during the canary and restore them at weight 0", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		annotations := getRoute().Annotations
+		Expect(annotations).To(HaveKeyWithValue(balanceAnnotation, "roundrobin"))
+		Expect(annotations).To(HaveKeyWithValue(disableCookiesAnnotation, "true"))
+		Expect(annotations).To(HaveKeyWithValue(timeoutAnnotation, "30s"))
+		Expect(annotations).To(HaveKey(originalAnnotationsKey))
+
+		// the originals are kept across weight changes
+		Expect(r.SetWeight(rollout, 60, nil).HasError()).To(BeFalse())
+
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations).To(Equal(map[string]string{
+			balanceAnnotation:   "source",
+			"example.com/other": "kept",
+		}))
+	})
+
+	It("should restore the annotations in RemoveManagedRoutes", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+
+		route := getRoute()
+		Expect(route.Annotations).To(Equal(map[string]string{
+			balanceAnnotation:   "source",
+			"example.com/other": "kept",
+		}))
+		// the weights are left alone
+		Expect(*route.Spec.To.Weight).To(Equal(int32(70)))
+	})
+
+	It("should not restore the annotations while the rollout still sends traffic to the canary", func() {
+		rollout.Status.Canary.Weights = &v1alpha1.TrafficWeights{Canary: v1alpha1.WeightDestination{Weight: 30}}
+		routeClient.ClearActions()
+		for range 3 {
+			Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+			Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		}
+
+		updates := 0
+		for _, action := range routeClient.Actions() {
+			if action.GetVerb() == "update" {
+				updates++
+			}
+		}
+		Expect(updates).To(Equal(1))
+		Expect(getRoute().Annotations).To(HaveKeyWithValue(balanceAnnotation, "roundrobin"))
+	})
+
+	It("should reject invalid load-balancing settings", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:        []string{mocks.RouteName},
+			LoadBalancing: &LoadBalancing{Balance: "fastest", Timeout: "soon"},
+		})
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.Error()).To(ContainSubstring(`loadBalancing.balance: Unsupported value: "fastest"`))
+		Expect(rpcErr.Error()).To(ContainSubstring(`loadBalancing.timeout: Invalid value: "soon"`))
+	})
+})
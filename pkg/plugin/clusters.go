@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	openshiftclientset "github.com/openshift/client-go/route/clientset/versioned"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// routeTarget is a Route managed by the plugin along with the client reaching its cluster
+type routeTarget struct {
+	// Cluster is the name of the remote cluster, empty for the local one
+	Cluster   string
+	Namespace string
+	Name      string
+
+	client openshiftclientset.Interface
+}
+
+func (t routeTarget) String() string {
+	if t.Cluster == "" {
+		return t.Namespace + "/" + t.Name
+	}
+	return t.Cluster + ":" + t.Namespace + "/" + t.Name
+}
+
+// clusterClient is a cached client of a remote cluster
+type clusterClient struct {
+	// resourceVersion of the kubeconfig Secret the client was built from
+	resourceVersion string
+	client          openshiftclientset.Interface
+}
+
+// clusterClients caches one route client per remote cluster, keyed by its kubeconfig Secret
+type clusterClients struct {
+	mu      sync.Mutex
+	clients map[string]clusterClient
+}
+
+// routeTargets resolves the Routes of the configuration to the clients of their clusters.
+func (r *RpcPlugin) routeTargets(ctx context.Context, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting) ([]routeTarget, error) {
+	var targets []routeTarget
+	for _, route := range openshift.Routes {
+		namespace, name := splitRouteReference(route, rollout.Namespace)
+		targets = append(targets, routeTarget{Namespace: namespace, Name: name, client: r.routeClient})
+	}
+
+	allowedSecretNamespaces := r.globalConfig().KubeconfigSecretNamespaces
+	for _, cluster := range openshift.ClusterRoutes {
+		secretNamespace := cluster.KubeconfigSecret.Namespace
+		if secretNamespace == "" {
+			secretNamespace = rollout.Namespace
+		}
+		// the plugin may read the Secrets of every namespace, the rollout author may not
+		if err := checkKubeconfigSecret(allowedSecretNamespaces, rollout, secretNamespace, cluster.KubeconfigSecret.Name); err != nil {
+			return nil, withCluster(err, cluster.Cluster)
+		}
+		client, err := r.clusterClient(ctx, cluster.KubeconfigSecret, rollout.Namespace)
+		if err != nil {
+			return nil, withCluster(err, cluster.Cluster)
+		}
+		for _, route := range cluster.Routes {
+			namespace, name := splitRouteReference(route, rollout.Namespace)
+			targets = append(targets, routeTarget{Cluster: cluster.Cluster, Namespace: namespace, Name: name, client: client})
+		}
+	}
+	return targets, nil
+}
+
+// clusterClient returns the route client of the cluster whose kubeconfig is stored in the given Secret,
+// building a new one when the Secret changed since the cached client was built.
+func (r *RpcPlugin) clusterClient(ctx context.Context, ref SecretKeyReference, defaultNamespace string) (openshiftclientset.Interface, error) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	key := ref.Key
+	if key == "" {
+		key = defaultKubeconfigKey
+	}
+
+	secret, err := r.kubeClient.CoreV1().Secrets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, newError(ReasonInvalidConfig, nil, "kubeconfig Secret %q not found in namespace %q", ref.Name, namespace)
+		}
+		return nil, err
+	}
+
+	cacheKey := namespace + "/" + ref.Name + "/" + key
+	r.clusters.mu.Lock()
+	defer r.clusters.mu.Unlock()
+	if cached, ok := r.clusters.clients[cacheKey]; ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached.client, nil
+	}
+
+	kubeconfig, ok := secret.Data[key]
+	if !ok {
+		return nil, newError(ReasonInvalidConfig, nil, "key %q not found in kubeconfig Secret %s/%s", key, namespace, ref.Name)
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, newError(ReasonInvalidConfig, err, "invalid kubeconfig in Secret %s/%s", namespace, ref.Name)
+	}
+	newClient := r.newClusterClient
+	if newClient == nil {
+		newClient = func(cfg *rest.Config) (openshiftclientset.Interface, error) {
+			return openshiftclientset.NewForConfig(cfg)
+		}
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.clusters.clients == nil {
+		r.clusters.clients = map[string]clusterClient{}
+	}
+	r.clusters.clients[cacheKey] = clusterClient{resourceVersion: secret.ResourceVersion, client: client}
+	return client, nil
+}
+
+// withCluster prefixes the message of err with the name of the cluster it happened in.
+func withCluster(err error, cluster string) error {
+	if cluster == "" {
+		return err
+	}
+	pluginErr := *classifyError(err)
+	pluginErr.Message = fmt.Sprintf("cluster %q: %s", cluster, pluginErr.Message)
+	return &pluginErr
+}
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	openshiftclientset "github.com/openshift/client-go/route/clientset/versioned"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+const remoteKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: edge
+  cluster:
+    server: https://edge.example.com:6443
+contexts:
+- name: edge
+  context:
+    cluster: edge
+    user: plugin
+current-context: edge
+users:
+- name: plugin
+  user:
+    token: secret-token
+`
+
+var _ = Describe("Test routes in remote clusters", func() {
+	var (
+		ctx          context.Context
+		localClient  *fake.Clientset
+		remoteClient *fake.Clientset
+		kubeClient   *k8sfake.Clientset
+		clientsBuilt int
+		r            *RpcPlugin
+	)
+
+	newClusterRollout := func(remoteRoutes ...string) *v1alpha1.Rollout {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes: []string{mocks.RouteName},
+			ClusterRoutes: []ClusterRoutes{{
+				Cluster:          "edge",
+				KubeconfigSecret: SecretKeyReference{Name: "edge-kubeconfig"},
+				Routes:           remoteRoutes,
+			}},
+		})
+		return rollout
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		localClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		remoteClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		kubeClient = k8sfake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "edge-kubeconfig", Namespace: mocks.Namespace, ResourceVersion: "1"},
+			Data:       map[string][]byte{defaultKubeconfigKey: []byte(remoteKubeconfig)},
+		})
+		clientsBuilt = 0
+		r = &RpcPlugin{
+			routeClient: localClient,
+			kubeClient:  kubeClient,
+			newClusterClient: func(cfg *rest.Config) (openshiftclientset.Interface, error) {
+				Expect(cfg.Host).To(Equal("https://edge.example.com:6443"))
+				clientsBuilt++
+				return remoteClient, nil
+			},
+		}
+	})
+
+	It("should set and verify the weight in every cluster", func() {
+		rollout := newClusterRollout(mocks.ValidRouteName)
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		for _, client := range []*fake.Clientset{localClient, remoteClient} {
+			name := mocks.RouteName
+			if client == remoteClient {
+				name = mocks.ValidRouteName
+			}
+			route, err := client.RouteV1().Routes(mocks.Namespace).Get(ctx, name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*route.Spec.To.Weight).To(Equal(int32(70)))
+			Expect(*route.Spec.AlternateBackends[0].Weight).To(Equal(int32(30)))
+		}
+
+		verified, rpcErr := r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+	})
+
+	It("should report errors per cluster and still update the other routes", func() {
+		rollout := newClusterRollout("missing")
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.Error()).To(HavePrefix(string(ReasonRouteNotFound) + `: cluster "edge": route "missing" not found`))
+
+		route, err := localClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*route.Spec.To.Weight).To(Equal(int32(70)))
+	})
+
+	It("should report a missing kubeconfig Secret", func() {
+		Expect(kubeClient.CoreV1().Secrets(mocks.Namespace).Delete(ctx, "edge-kubeconfig", metav1.DeleteOptions{})).To(Succeed())
+		rpcErr := r.SetWeight(newClusterRollout(mocks.ValidRouteName), 30, nil)
+		Expect(rpcErr.Error()).To(HavePrefix(string(ReasonInvalidConfig) + `: cluster "edge": kubeconfig Secret "edge-kubeconfig" not found`))
+	})
+
+	It("should cache the client until the Secret changes", func() {
+		rollout := newClusterRollout(mocks.ValidRouteName)
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(r.SetWeight(rollout, 40, nil).HasError()).To(BeFalse())
+		Expect(clientsBuilt).To(Equal(1))
+
+		secret, err := kubeClient.CoreV1().Secrets(mocks.Namespace).Get(ctx, "edge-kubeconfig", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		secret.ResourceVersion = "2"
+		_, err = kubeClient.CoreV1().Secrets(mocks.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.SetWeight(rollout, 50, nil).HasError()).To(BeFalse())
+		Expect(clientsBuilt).To(Equal(2))
+	})
+})
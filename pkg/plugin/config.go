@@ -0,0 +1,411 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// PluginName is the name the plugin is registered under by default, and the key of its configuration
+// in the rollout's trafficRouting.plugins map
+const PluginName = "argoproj-labs/openshift"
+
+// OpenshiftTrafficRouting defines the configuration required to use Openshift routes for traffic
+type OpenshiftTrafficRouting struct {
+	// Routes is an array of strings which refer to the names of the Routes used to route traffic to the service
+	Routes []string `json:"routes" protobuf:"bytes,1,name=routes"`
+	// ClusterRoutes lists Routes that live in remote clusters
+	ClusterRoutes []ClusterRoutes `json:"clusterRoutes,omitempty"`
+	// LoadBalancing configures the router annotations applied to the routes while the canary receives traffic
+	LoadBalancing *LoadBalancing `json:"loadBalancing,omitempty"`
+	// Mode selects how traffic is shifted to the canary, weighted by default
+	Mode TrafficMode `json:"mode,omitempty"`
+	// Affinity configures the affinity mode
+	Affinity *Affinity `json:"affinity,omitempty"`
+	// RouterShards are the names of the routers, as in status.ingress[].routerName, that must admit a route
+	// before its weight counts as applied. Every router reporting a status must admit it when empty.
+	RouterShards []string `json:"routerShards,omitempty"`
+	// TLSPolicy selects what to do with TLS settings known to break a canary, Warn by default
+	TLSPolicy TLSPolicy `json:"tlsPolicy,omitempty"`
+	// VerifyCanaryCertificate checks, for re-encrypt routes with a destination CA, that the
+	// serving certificate of the canary Service is trusted by that CA
+	VerifyCanaryCertificate bool `json:"verifyCanaryCertificate,omitempty"`
+	// PreviewRoute configures a Route reaching only the canary while the canary is in progress
+	PreviewRoute *PreviewRoute `json:"previewRoute,omitempty"`
+	// RouteTemplate describes the Routes created, owned by the rollout, when a route of Routes does not exist
+	RouteTemplate *RouteTemplate `json:"routeTemplate,omitempty"`
+	// WeightScale is the sum of the backend weights written to the routes, between 100 and 256, 100 by default.
+	// OpenShift accepts weights up to 256, which keeps the ratio of the backends closer to the one requested.
+	WeightScale int32 `json:"weightScale,omitempty"`
+	// DriftPolicy selects what to do with routes whose backends were changed outside the plugin, Ignore by default
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+	// MaxCanaryWeight is the highest weight the canary steps may send to the canary, unlimited when 0
+	MaxCanaryWeight int32 `json:"maxCanaryWeight,omitempty"`
+	// MaxWeightIncrease is the most a canary step may raise the canary weight recorded in the status
+	// of the rollout, unlimited when 0
+	MaxWeightIncrease int32 `json:"maxWeightIncrease,omitempty"`
+	// WeightLimitPolicy selects what to do with weights above MaxCanaryWeight, Fail by default
+	WeightLimitPolicy WeightLimitPolicy `json:"weightLimitPolicy,omitempty"`
+}
+
+// ClusterRoutes refers to Routes in a remote cluster reached through a kubeconfig stored in a Secret
+type ClusterRoutes struct {
+	// Cluster is the name of the remote cluster, used in logs and errors
+	Cluster string `json:"cluster"`
+	// KubeconfigSecret refers to the Secret holding the kubeconfig of the remote cluster
+	KubeconfigSecret SecretKeyReference `json:"kubeconfigSecret"`
+	// Routes refer to the Routes in the remote cluster, as <name> or <namespace>/<name>
+	Routes []string `json:"routes"`
+}
+
+// SecretKeyReference refers to a key of a Secret in the local cluster
+type SecretKeyReference struct {
+	// Namespace of the Secret, defaults to the namespace of the rollout. Other namespaces
+	// must be allowed by kubeconfigSecretNamespaces in the plugin configuration file.
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the Secret
+	Name string `json:"name"`
+	// Key holding the kubeconfig, defaults to "kubeconfig"
+	Key string `json:"key,omitempty"`
+}
+
+const defaultKubeconfigKey = "kubeconfig"
+
+// getOpenshiftRouting returns the validated plugin configuration of the rollout.
+func getOpenshiftRouting(rollout *v1alpha1.Rollout, names ...string) (*OpenshiftTrafficRouting, error) {
+	openshift, _, errs := rolloutConfig(rollout, names)
+	if len(errs) > 0 {
+		return nil, invalidConfigError(errs)
+	}
+	return openshift, nil
+}
+
+// rolloutConfig decodes and validates the plugin configuration of the rollout, returning the path
+// of the configuration block and every problem found.
+func rolloutConfig(rollout *v1alpha1.Rollout, names []string) (*OpenshiftTrafficRouting, *field.Path, field.ErrorList) {
+	canaryPath := field.NewPath("spec", "strategy", "canary")
+	if rollout == nil || rollout.Spec.Strategy.Canary == nil {
+		return nil, nil, field.ErrorList{field.Required(canaryPath, "the rollout must use the canary strategy")}
+	}
+	trafficRoutingPath := canaryPath.Child("trafficRouting")
+	if rollout.Spec.Strategy.Canary.TrafficRouting == nil {
+		return nil, nil, field.ErrorList{field.Required(trafficRoutingPath, "the rollout must configure traffic routing")}
+	}
+
+	plugins := rollout.Spec.Strategy.Canary.TrafficRouting.Plugins
+	pluginsPath := trafficRoutingPath.Child("plugins")
+	key, ok := PluginConfigKey(plugins, names...)
+	if !ok {
+		found := "none"
+		if len(plugins) > 0 {
+			found = strings.Join(sortedKeys(plugins), ", ")
+		}
+		msg := fmt.Sprintf("no configuration block for the plugin, expected one of %s, found %s",
+			strings.Join(pluginNames(names), ", "), found)
+		return nil, nil, field.ErrorList{field.Required(pluginsPath, msg)}
+	}
+	pluginPath := pluginsPath.Key(key)
+	openshift, errs := parseConfig(plugins[key], pluginPath)
+	return openshift, pluginPath, errs
+}
+
+// PluginConfigKey returns the key of the plugin configuration in the rollout's trafficRouting.plugins map:
+// PluginName or the first of the other names the plugin is registered under that the map holds, otherwise
+// the only key of the map, since the rollouts controller only calls the plugin for the rollouts configuring it.
+func PluginConfigKey(plugins map[string]json.RawMessage, names ...string) (string, bool) {
+	for _, name := range pluginNames(names) {
+		if _, ok := plugins[name]; ok {
+			return name, true
+		}
+	}
+	if len(plugins) == 1 {
+		for key := range plugins {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// pluginNames returns PluginName followed by the other names the plugin is registered under.
+func pluginNames(names []string) []string {
+	return append([]string{PluginName}, names...)
+}
+
+// ValidateRollout returns every problem of the plugin configuration of the rollout
+// and of the parts of the rollout the plugin relies on.
+// names are the names the plugin is registered under besides PluginName.
+func ValidateRollout(rollout *v1alpha1.Rollout, names ...string) field.ErrorList {
+	_, _, errs := rolloutConfig(rollout, names)
+	if rollout == nil || rollout.Spec.Strategy.Canary == nil {
+		return errs
+	}
+
+	canary := rollout.Spec.Strategy.Canary
+	canaryPath := field.NewPath("spec", "strategy", "canary")
+	if canary.StableService == "" {
+		errs = append(errs, field.Required(canaryPath.Child("stableService"), "the plugin sends the traffic of the routes to the stable service"))
+	}
+	if canary.CanaryService == "" {
+		errs = append(errs, field.Required(canaryPath.Child("canaryService"), "the plugin sends the canary traffic of the routes to the canary service"))
+	}
+	for i, step := range canary.Steps {
+		stepPath := canaryPath.Child("steps").Index(i)
+		if step.SetHeaderRoute != nil {
+			errs = append(errs, field.Forbidden(stepPath.Child("setHeaderRoute"), "header based routing is not supported by the plugin"))
+		}
+		if step.SetMirrorRoute != nil {
+			errs = append(errs, field.Forbidden(stepPath.Child("setMirrorRoute"), "traffic mirroring is not supported by the plugin"))
+		}
+	}
+	return errs
+}
+
+// ConfigFromRollout returns the validated plugin configuration of the rollout,
+// names being the names the plugin is registered under besides PluginName.
+func ConfigFromRollout(rollout *v1alpha1.Rollout, names ...string) (*OpenshiftTrafficRouting, error) {
+	return getOpenshiftRouting(rollout, names...)
+}
+
+// ParseConfig decodes and validates a standalone plugin configuration block.
+func ParseConfig(raw []byte) (*OpenshiftTrafficRouting, error) {
+	openshift, errs := parseConfig(raw, field.NewPath(PluginName))
+	if len(errs) > 0 {
+		return nil, invalidConfigError(errs)
+	}
+	return openshift, nil
+}
+
+// parseConfig decodes and validates a raw plugin configuration, returning every problem found.
+func parseConfig(raw json.RawMessage, path *field.Path) (*OpenshiftTrafficRouting, field.ErrorList) {
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, field.ErrorList{field.Required(path, "the plugin configuration is missing")}
+	}
+
+	errs := unknownFields(raw, reflect.TypeOf(OpenshiftTrafficRouting{}), path)
+
+	var openshift OpenshiftTrafficRouting
+	if err := json.Unmarshal(raw, &openshift); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			errs = append(errs, field.TypeInvalid(fieldPath(path, typeErr.Field), typeErr.Value, "must be of type "+typeErr.Type.String()))
+		} else {
+			errs = append(errs, field.Invalid(path, string(raw), err.Error()))
+		}
+		return nil, errs
+	}
+
+	errs = append(errs, validateConfig(&openshift, path)...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &openshift, nil
+}
+
+// validateConfig checks the semantics of a decoded plugin configuration.
+func validateConfig(openshift *OpenshiftTrafficRouting, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	routesPath := path.Child("routes")
+	if len(openshift.Routes) == 0 && len(openshift.ClusterRoutes) == 0 {
+		errs = append(errs, field.Required(routesPath, "at least one route must be specified"))
+	}
+	errs = append(errs, validateRouteReferences(openshift.Routes, routesPath)...)
+
+	clusters := map[string]bool{}
+	for i, cluster := range openshift.ClusterRoutes {
+		clusterPath := path.Child("clusterRoutes").Index(i)
+		if cluster.Cluster == "" {
+			errs = append(errs, field.Required(clusterPath.Child("cluster"), "the cluster name must not be empty"))
+		} else if clusters[cluster.Cluster] {
+			errs = append(errs, field.Duplicate(clusterPath.Child("cluster"), cluster.Cluster))
+		}
+		clusters[cluster.Cluster] = true
+
+		secretPath := clusterPath.Child("kubeconfigSecret")
+		if cluster.KubeconfigSecret.Name == "" {
+			errs = append(errs, field.Required(secretPath.Child("name"), "the kubeconfig Secret must be specified"))
+		}
+		if cluster.KubeconfigSecret.Namespace != "" {
+			for _, msg := range validation.IsDNS1123Label(cluster.KubeconfigSecret.Namespace) {
+				errs = append(errs, field.Invalid(secretPath.Child("namespace"), cluster.KubeconfigSecret.Namespace, msg))
+			}
+		}
+
+		if len(cluster.Routes) == 0 {
+			errs = append(errs, field.Required(clusterPath.Child("routes"), "at least one route must be specified"))
+		}
+		errs = append(errs, validateRouteReferences(cluster.Routes, clusterPath.Child("routes"))...)
+	}
+
+	errs = append(errs, validateLoadBalancing(openshift.LoadBalancing, path.Child("loadBalancing"))...)
+	errs = append(errs, validateAffinity(openshift, path)...)
+	errs = append(errs, validateRouterShards(openshift.RouterShards, path.Child("routerShards"))...)
+	errs = append(errs, validateTLSPolicy(openshift.TLSPolicy, path.Child("tlsPolicy"))...)
+	errs = append(errs, validatePreviewRoute(openshift, path.Child("previewRoute"))...)
+	errs = append(errs, validateRouteTemplate(openshift, path.Child("routeTemplate"))...)
+	errs = append(errs, validateWeightScale(openshift.WeightScale, path.Child("weightScale"))...)
+	errs = append(errs, validateDriftPolicy(openshift.DriftPolicy, path.Child("driftPolicy"))...)
+	errs = append(errs, validateWeightLimit(openshift.MaxCanaryWeight, path.Child("maxCanaryWeight"))...)
+	errs = append(errs, validateWeightLimit(openshift.MaxWeightIncrease, path.Child("maxWeightIncrease"))...)
+	errs = append(errs, validateWeightLimitPolicy(openshift.WeightLimitPolicy, path.Child("weightLimitPolicy"))...)
+	return errs
+}
+
+// validateRouteReferences checks a list of route references and reports duplicates.
+func validateRouteReferences(routes []string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, route := range routes {
+		errs = append(errs, validateRouteReference(route, path.Index(i))...)
+		if seen[route] {
+			errs = append(errs, field.Duplicate(path.Index(i), route))
+		}
+		seen[route] = true
+	}
+	return errs
+}
+
+// validateRouteReference checks a route reference of the form <name> or <namespace>/<name>.
+func validateRouteReference(ref string, path *field.Path) field.ErrorList {
+	if ref == "" {
+		return field.ErrorList{field.Required(path, "the route name must not be empty")}
+	}
+
+	parts := strings.Split(ref, "/")
+	if len(parts) > 2 {
+		return field.ErrorList{field.Invalid(path, ref, "must be of the form <name> or <namespace>/<name>")}
+	}
+
+	var errs field.ErrorList
+	name := parts[len(parts)-1]
+	if len(parts) == 2 {
+		if parts[0] == "" {
+			errs = append(errs, field.Invalid(path, ref, "the namespace must not be empty"))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(parts[0]) {
+				errs = append(errs, field.Invalid(path, ref, "invalid namespace: "+msg))
+			}
+		}
+	}
+	if name == "" {
+		errs = append(errs, field.Invalid(path, ref, "the route name must not be empty"))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(path, ref, "invalid route name: "+msg))
+		}
+	}
+	return errs
+}
+
+// splitRouteReference returns the namespace and name of a validated route reference,
+// defaulting the namespace to the one of the rollout.
+func splitRouteReference(ref, defaultNamespace string) (string, string) {
+	if namespace, name, found := strings.Cut(ref, "/"); found {
+		return namespace, name
+	}
+	return defaultNamespace, ref
+}
+
+// unknownFields reports every key of raw that has no matching json field in t, recursing into nested objects.
+func unknownFields(raw json.RawMessage, t reflect.Type, path *field.Path) field.ErrorList {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			// type mismatches are reported when decoding
+			return nil
+		}
+		known := jsonFields(t)
+		var errs field.ErrorList
+		for _, key := range sortedKeys(object) {
+			fieldType, ok := known[key]
+			if !ok {
+				errs = append(errs, field.NotSupported(path.Child(key), key, sortedKeys(known)))
+				continue
+			}
+			errs = append(errs, unknownFields(object[key], fieldType, path.Child(key))...)
+		}
+		return errs
+	case reflect.Slice:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil
+		}
+		var errs field.ErrorList
+		for i, item := range items {
+			errs = append(errs, unknownFields(item, t.Elem(), path.Index(i))...)
+		}
+		return errs
+	case reflect.Map:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil
+		}
+		var errs field.ErrorList
+		for _, key := range sortedKeys(object) {
+			errs = append(errs, unknownFields(object[key], t.Elem(), path.Key(key))...)
+		}
+		return errs
+	}
+	return nil
+}
+
+// jsonFields returns the json field names of a struct type along with their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// fieldPath converts the dotted field name of a json.UnmarshalTypeError into a child of path.
+func fieldPath(path *field.Path, dotted string) *field.Path {
+	for _, name := range strings.Split(dotted, ".") {
+		path = path.Child(name)
+	}
+	return path
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// invalidConfigError wraps a list of validation errors into a single InvalidConfig error.
+func invalidConfigError(errs field.ErrorList) error {
+	return newError(ReasonInvalidConfig, errs.ToAggregate(), "invalid plugin configuration")
+}
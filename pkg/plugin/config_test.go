@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("Test plugin configuration validation", func() {
+	path := field.NewPath("plugin")
+
+	DescribeTable("parseConfig reports every problem with its field path",
+		func(raw string, expected ...string) {
+			openshift, errs := parseConfig(json.RawMessage(raw), path)
+			Expect(openshift).To(BeNil())
+			Expect(errs).To(HaveLen(len(expected)))
+			for i, msg := range expected {
+				Expect(errs[i].Error()).To(HavePrefix(msg))
+			}
+		},
+		Entry("missing configuration", ``, "plugin: Required value"),
+		Entry("null configuration", `null`, "plugin: Required value"),
+		Entry("empty routes", `{"routes":[]}`, "plugin.routes: Required value"),
+		Entry("empty route name", `{"routes":[""]}`, "plugin.routes[0]: Required value"),
+		Entry("malformed reference", `{"routes":["ns/name/extra"]}`, `plugin.routes[0]: Invalid value: "ns/name/extra"`),
+		Entry("empty namespace", `{"routes":["/name"]}`, `plugin.routes[0]: Invalid value: "/name": the namespace must not be empty`),
+		Entry("invalid route name", `{"routes":["Route_1"]}`, `plugin.routes[0]: Invalid value: "Route_1": invalid route name`),
+		Entry("duplicate route", `{"routes":["a","a"]}`, `plugin.routes[1]: Duplicate value: "a"`),
+		Entry("wrong type", `{"routes":"a"}`, "plugin.routes: Invalid value"),
+		Entry("cluster routes without secret and routes", `{"clusterRoutes":[{"cluster":"edge","kubeconfigSecret":{}}]}`,
+			"plugin.clusterRoutes[0].kubeconfigSecret.name: Required value",
+			"plugin.clusterRoutes[0].routes: Required value"),
+		Entry("duplicate cluster", `{"clusterRoutes":[{"cluster":"a","kubeconfigSecret":{"name":"s"},"routes":["r"]},{"cluster":"a","kubeconfigSecret":{"name":"s"},"routes":["r"]}]}`,
+			`plugin.clusterRoutes[1].cluster: Duplicate value: "a"`),
+		Entry("unknown key in cluster routes", `{"clusterRoutes":[{"cluster":"a","kubeconfigSecret":{"name":"s","file":"x"},"routes":["r"]}]}`,
+			`plugin.clusterRoutes[0].kubeconfigSecret.file: Unsupported value: "file"`),
+		Entry("every problem at once", `{"routes":["","a/b/c"],"namespace":"x","weight":1}`,
+			`plugin.namespace: Unsupported value: "namespace"`,
+			`plugin.weight: Unsupported value: "weight"`,
+			"plugin.routes[0]: Required value",
+			`plugin.routes[1]: Invalid value: "a/b/c"`),
+	)
+
+	It("should accept only cluster routes", func() {
+		openshift, errs := parseConfig(json.RawMessage(`{"clusterRoutes":[{"cluster":"edge","kubeconfigSecret":{"name":"s"},"routes":["r"]}]}`), path)
+		Expect(errs).To(BeEmpty())
+		Expect(openshift.ClusterRoutes).To(HaveLen(1))
+	})
+
+	It("should accept routes with and without a namespace", func() {
+		openshift, errs := parseConfig(json.RawMessage(`{"routes":["a","other/b"]}`), path)
+		Expect(errs).To(BeEmpty())
+		Expect(openshift.Routes).To(Equal([]string{"a", "other/b"}))
+	})
+
+	It("should report a missing canary strategy instead of panicking", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.Spec.Strategy.Canary = nil
+		_, err := getOpenshiftRouting(rollout)
+		Expect(ReasonOf(err)).To(Equal(ReasonInvalidConfig))
+		Expect(err.Error()).To(ContainSubstring("spec.strategy.canary: Required value"))
+	})
+
+	It("should report missing traffic routing instead of panicking", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.Spec.Strategy.Canary.TrafficRouting = nil
+		_, err := getOpenshiftRouting(rollout)
+		Expect(ReasonOf(err)).To(Equal(ReasonInvalidConfig))
+		Expect(err.Error()).To(ContainSubstring("spec.strategy.canary.trafficRouting: Required value"))
+	})
+
+	DescribeTable("PluginConfigKey finds the block of the plugin",
+		func(keys []string, names []string, expected string) {
+			plugins := map[string]json.RawMessage{}
+			for _, key := range keys {
+				plugins[key] = json.RawMessage(`{}`)
+			}
+			key, ok := PluginConfigKey(plugins, names...)
+			Expect(ok).To(Equal(expected != ""))
+			Expect(key).To(Equal(expected))
+		},
+		Entry("default name", []string{PluginName, "other"}, nil, PluginName),
+		Entry("default name first", []string{PluginName, "mirror/openshift"}, []string{"mirror/openshift"}, PluginName),
+		Entry("other name", []string{"mirror/openshift", "other"}, []string{"mirror/openshift"}, "mirror/openshift"),
+		Entry("other names in order", []string{"v2", "v3"}, []string{"v3", "v2"}, "v3"),
+		Entry("only block", []string{"mirror/openshift"}, nil, "mirror/openshift"),
+		Entry("several unknown blocks", []string{"a", "b"}, nil, ""),
+		Entry("no block", nil, nil, ""),
+	)
+
+	It("should report a missing block with the names expected and found", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.Spec.Strategy.Canary.TrafficRouting.Plugins = map[string]json.RawMessage{"b": nil, "a": nil}
+		_, err := getOpenshiftRouting(rollout, "mirror/openshift")
+		Expect(ReasonOf(err)).To(Equal(ReasonInvalidConfig))
+		Expect(err.Error()).To(ContainSubstring("spec.strategy.canary.trafficRouting.plugins: Required value: " +
+			"no configuration block for the plugin, expected one of argoproj-labs/openshift, mirror/openshift, found a, b"))
+	})
+
+	It("should report problems under the key of the block found", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.Spec.Strategy.Canary.TrafficRouting.Plugins = map[string]json.RawMessage{"mirror/openshift": json.RawMessage(`{"routes":[]}`)}
+		_, err := getOpenshiftRouting(rollout)
+		Expect(err.Error()).To(ContainSubstring("spec.strategy.canary.trafficRouting.plugins[mirror/openshift].routes: Required value"))
+	})
+
+	It("should split route references", func() {
+		namespace, name := splitRouteReference("route", "default")
+		Expect(namespace).To(Equal("default"))
+		Expect(name).To(Equal("route"))
+
+		namespace, name = splitRouteReference("other/route", "default")
+		Expect(namespace).To(Equal("other"))
+		Expect(name).To(Equal("route"))
+	})
+})
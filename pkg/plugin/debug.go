@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/utils"
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+// DebugHandler serves the debug endpoints of the plugin:
+// /healthz checks that the API server serves the Route API, /debug/state dumps the state of the plugin,
+// /debug/loglevel reads or, with PUT, changes the log level, and /debug/pprof/ serves the Go profiles.
+func (r *RpcPlugin) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", r.serveHealth)
+	mux.HandleFunc("/debug/state", r.serveState)
+	mux.HandleFunc("/debug/loglevel", serveLogLevel)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Healthy checks that the API server of the local cluster can be reached and serves the Route API.
+// It relies on discovery, which every authenticated client may use, rather than on the permissions
+// granted to the plugin, which may be limited to some namespaces.
+func (r *RpcPlugin) Healthy(ctx context.Context) error {
+	r.clientsMu.RLock()
+	routeClient := r.routeClient
+	r.clientsMu.RUnlock()
+	if routeClient == nil {
+		return errors.New("the plugin is not initialized")
+	}
+
+	_, err := routeClient.Discovery().ServerResourcesForGroupVersion(routev1.GroupVersion.String())
+	return err
+}
+
+func (r *RpcPlugin) serveHealth(w http.ResponseWriter, req *http.Request) {
+	if err := r.Healthy(req.Context()); err != nil {
+		slog.Warn("health check failed", slog.Any("err", err))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (r *RpcPlugin) serveState(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r.State()); err != nil {
+		slog.Error("unable to write the state of the plugin", slog.Any("err", err))
+	}
+}
+
+// serveLogLevel returns the log level, or changes it to the level given in the body of a PUT request.
+func serveLogLevel(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(req.Body, 64))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(strings.TrimSpace(string(body)))); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		utils.SetLogLevel(lvl)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "only GET and PUT are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Fprintln(w, utils.LogLevel().String())
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/utils"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Test the debug endpoints", func() {
+	var (
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+		now         time.Time
+	)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		r.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	BeforeEach(func() {
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		routeClient.Resources = []*metav1.APIResourceList{{GroupVersion: routev1.GroupVersion.String()}}
+		r = &RpcPlugin{routeClient: routeClient}
+		now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		r.state.now = func() time.Time { return now }
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+	})
+
+	It("should report whether the API server serves the routes", func() {
+		response := get("/healthz")
+		Expect(response.Code).To(Equal(http.StatusOK))
+
+		// the API server of a cluster without the Route API
+		routeClient.Resources = nil
+		response = get("/healthz")
+		Expect(response.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(response.Body.String()).To(ContainSubstring(`GroupVersion "route.openshift.io/v1" not found`))
+
+		Expect((&RpcPlugin{}).Healthy(context.Background())).To(MatchError("the plugin is not initialized"))
+	})
+
+	It("should dump the weights, verifications and errors of the rollouts", func() {
+		r.UpdateHash(rollout, "abc123", "", nil)
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		now = now.Add(time.Minute)
+		verified, _ := r.VerifyWeight(rollout, 40, nil)
+		Expect(verified).To(Equal(pluginTypes.NotVerified))
+		r.VerifyWeight(rollout, 40, nil)
+
+		other := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, "missing")
+		other.Name = "other"
+		Expect(r.SetWeight(other, 30, nil).HasError()).To(BeTrue())
+
+		response := get("/debug/state")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		var state State
+		Expect(json.Unmarshal(response.Body.Bytes(), &state)).To(Succeed())
+		Expect(state.Errors).To(Equal(map[ErrorReason]int{ReasonRouteNotFound: 1}))
+		Expect(state.Rollouts).To(HaveLen(2))
+
+		Expect(state.Rollouts[0].Rollout).To(Equal("default/other"))
+		Expect(state.Rollouts[0].Weight).To(BeNil())
+		Expect(state.Rollouts[0].LastError).To(HavePrefix("OpenshiftRouteNotFound"))
+
+		applied := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		Expect(state.Rollouts[1]).To(Equal(RolloutState{
+			Rollout:    "default/rollout",
+			Routes:     []string{"default/argo-rollouts"},
+			CanaryHash: "abc123",
+			Weight:     ptr.To[int32](30),
+			AppliedAt:  &applied,
+			PendingVerification: &PendingVerification{
+				Weight:   40,
+				Since:    applied.Add(time.Minute),
+				Attempts: 2,
+			},
+		}))
+
+		Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+		Expect(r.State().Rollouts).To(HaveExactElements(HaveField("Rollout", "default/other")))
+	})
+
+	It("should read and change the log level", func() {
+		original := utils.LogLevel()
+		DeferCleanup(func() { utils.SetLogLevel(original) })
+
+		recorder := httptest.NewRecorder()
+		r.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader("debug\n")))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(Equal("DEBUG\n"))
+		Expect(utils.LogLevel()).To(Equal(slog.LevelDebug))
+		Expect(get("/debug/loglevel").Body.String()).To(Equal("DEBUG\n"))
+
+		recorder = httptest.NewRecorder()
+		r.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader("loud")))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		Expect(utils.LogLevel()).To(Equal(slog.LevelDebug))
+	})
+
+	It("should serve the Go profiles", func() {
+		Expect(get("/debug/pprof/").Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"log/slog"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DriftPolicy selects what the plugin does with routes whose backends were changed outside the plugin
+type DriftPolicy string
+
+const (
+	// DriftPolicyIgnore overwrites the backends of the route
+	DriftPolicyIgnore DriftPolicy = "Ignore"
+	// DriftPolicyWarn overwrites the backends of the route and logs the drift
+	DriftPolicyWarn DriftPolicy = "Warn"
+	// DriftPolicyFail refuses to send traffic to the canary through the route
+	DriftPolicyFail DriftPolicy = "Fail"
+)
+
+func validateDriftPolicy(policy DriftPolicy, path *field.Path) field.ErrorList {
+	policies := []string{string(DriftPolicyIgnore), string(DriftPolicyWarn), string(DriftPolicyFail)}
+	if policy != "" && !slices.Contains(policies, string(policy)) {
+		return field.ErrorList{field.NotSupported(path, policy, policies)}
+	}
+	return nil
+}
+
+// backendDrift returns the backends of the route that are neither the stable nor the canary service of the rollout.
+func backendDrift(route *routev1.Route, rollout *v1alpha1.Rollout) []string {
+	canary := rollout.Spec.Strategy.Canary
+	var drift []string
+	if route.Spec.To.Name != canary.StableService {
+		drift = append(drift, fmt.Sprintf("the default backend is %q instead of the stable service %q", route.Spec.To.Name, canary.StableService))
+	}
+	for _, backend := range route.Spec.AlternateBackends {
+		if backend.Name != canary.CanaryService {
+			drift = append(drift, fmt.Sprintf("the alternate backend %q is not the canary service %q", backend.Name, canary.CanaryService))
+		}
+	}
+	return drift
+}
+
+// backendsMatch tells whether the route sends stableWeight to the stable service and canaryWeight
+// to the canary service, and no traffic to other services.
+func backendsMatch(route *routev1.Route, rollout *v1alpha1.Rollout, stableWeight, canaryWeight int32) bool {
+	if len(backendDrift(route, rollout)) > 0 {
+		return false
+	}
+	routeStable, routeCanary := routeWeights(route)
+	return routeStable == stableWeight && routeCanary == canaryWeight
+}
+
+// checkDrift applies the drift policy to the route before its weights are changed.
+// Sending all the traffic back to the stable service is never refused.
+func checkDrift(route *routev1.Route, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting, desiredWeight int32) error {
+	if openshift.DriftPolicy == "" || openshift.DriftPolicy == DriftPolicyIgnore {
+		return nil
+	}
+	drift := backendDrift(route, rollout)
+	if len(drift) == 0 {
+		return nil
+	}
+	if openshift.DriftPolicy == DriftPolicyFail && desiredWeight > 0 {
+		return &PluginError{
+			Reason:  ReasonConflict,
+			Message: "the backends of route " + route.Namespace + "/" + route.Name + " were changed outside the plugin: " + strings.Join(drift, ", "),
+			Hint:    "put back the stable and canary services as the only backends of the route, or set driftPolicy to Warn or Ignore",
+		}
+	}
+	slog.Warn("the backends of the route were changed outside the plugin and are overwritten",
+		slog.String("route", route.Namespace+"/"+route.Name), slog.Any("drift", drift))
+	return nil
+}
@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Test the drift policy", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	getRoute := func() *routev1.Route {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return route
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+
+		// someone sends part of the traffic to another service
+		route := getRoute()
+		route.Spec.To.Name = mocks.StableServiceName
+		route.Spec.AlternateBackends = []routev1.RouteTargetReference{{Kind: "Service", Name: "hotfix", Weight: ptr.To[int32](10)}}
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should overwrite the backends by default", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends).To(HaveExactElements(HaveField("Name", mocks.CanaryServiceName)))
+	})
+
+	It("should refuse to send traffic to the canary with the Fail policy", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, DriftPolicy: DriftPolicyFail})
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.ErrorString).To(HavePrefix("OpenshiftConflict: the backends of route default/argo-rollouts were changed outside the plugin: " +
+			`the alternate backend "hotfix" is not the canary service "argo-rollouts-canary"`))
+		Expect(getRoute().Spec.AlternateBackends).To(HaveExactElements(HaveField("Name", "hotfix")))
+
+		// going back to the stable service is always allowed
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends).To(BeEmpty())
+	})
+
+	It("should overwrite the backend names when the drifted weights are already the desired ones", func() {
+		route := getRoute()
+		route.Spec.To = routev1.RouteTargetReference{Kind: "Service", Name: "someone-else", Weight: ptr.To[int32](70)}
+		route.Spec.AlternateBackends = []routev1.RouteTargetReference{{Kind: "Service", Name: "hotfix", Weight: ptr.To[int32](30)}}
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, DriftPolicy: DriftPolicyWarn})
+
+		verified, rpcErr := r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.NotVerified))
+
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		route = getRoute()
+		Expect(route.Spec.To.Name).To(Equal(mocks.StableServiceName))
+		Expect(route.Spec.To.Weight).To(HaveValue(Equal(int32(70))))
+		Expect(route.Spec.AlternateBackends).To(HaveExactElements(And(
+			HaveField("Name", mocks.CanaryServiceName), HaveField("Weight", HaveValue(Equal(int32(30)))))))
+
+		verified, rpcErr = r.VerifyWeight(rollout, 30, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+	})
+
+	It("should reject an unknown policy", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, DriftPolicy: "Overwrite"})
+		Expect(r.SetWeight(rollout, 30, nil).ErrorString).To(ContainSubstring(`driftPolicy: Unsupported value: "Overwrite"`))
+	})
+})
@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	routev1 "github.com/openshift/api/route/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrorReason identifies the category of a failure reported by the plugin.
+// It is rendered as the prefix of RpcError.ErrorString so that alerting on
+// the rollout status can match on it.
+type ErrorReason string
+
+const (
+	ReasonInvalidConfig  ErrorReason = "OpenshiftInvalidConfig"
+	ReasonRouteNotFound  ErrorReason = "OpenshiftRouteNotFound"
+	ReasonServiceMissing ErrorReason = "OpenshiftServiceMissing"
+	ReasonConflict       ErrorReason = "OpenshiftConflict"
+	ReasonForbidden      ErrorReason = "OpenshiftForbidden"
+	ReasonRouterRejected ErrorReason = "OpenshiftRouterRejected"
+	ReasonTimeout        ErrorReason = "OpenshiftTimeout"
+	ReasonWeightLimit    ErrorReason = "OpenshiftWeightLimitExceeded"
+	ReasonInternal       ErrorReason = "OpenshiftInternalError"
+)
+
+// remediationHints holds the default hint appended to an error of a given reason.
+var remediationHints = map[ErrorReason]string{
+	ReasonInvalidConfig:  "fix the block of the plugin under spec.strategy.canary.trafficRouting.plugins",
+	ReasonRouteNotFound:  "create the Route or correct its name in the plugin configuration",
+	ReasonServiceMissing: "set spec.strategy.canary.stableService and canaryService and make sure both Services exist",
+	ReasonConflict:       "the Route was modified concurrently, the update is retried on the next reconciliation",
+	ReasonForbidden:      "grant the argo-rollouts service account access to routes.route.openshift.io (see yaml/rbac.yaml)",
+	ReasonRouterRejected: "inspect status.ingress[].conditions of the Route for the reason given by the router",
+	ReasonTimeout:        "check that the API server is reachable from the argo-rollouts controller",
+	ReasonWeightLimit:    "correct the setWeight steps of the rollout or raise the limit in the plugin configuration, aborting the rollout sends the traffic back to the stable service",
+}
+
+// PluginError is an error with a stable reason and a remediation hint.
+type PluginError struct {
+	Reason  ErrorReason
+	Message string
+	Hint    string
+	Err     error
+}
+
+// Error renders the error as "<Reason>: <message>[: <cause>] (hint: <hint>)".
+func (e *PluginError) Error() string {
+	msg := string(e.Reason) + ": " + e.Message
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Hint != "" {
+		msg += " (hint: " + e.Hint + ")"
+	}
+	return msg
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+// newError returns a PluginError of the given reason with its default hint.
+func newError(reason ErrorReason, err error, format string, args ...any) *PluginError {
+	return &PluginError{
+		Reason:  reason,
+		Message: fmt.Sprintf(format, args...),
+		Hint:    remediationHints[reason],
+		Err:     err,
+	}
+}
+
+// ReasonOf returns the reason of the first PluginError in err's chain,
+// or ReasonInternal if there is none.
+func ReasonOf(err error) ErrorReason {
+	var pluginErr *PluginError
+	if errors.As(err, &pluginErr) {
+		return pluginErr.Reason
+	}
+	return ReasonInternal
+}
+
+// classifyError maps an arbitrary error onto the plugin's error taxonomy.
+func classifyError(err error) *PluginError {
+	var pluginErr *PluginError
+	if errors.As(err, &pluginErr) {
+		return pluginErr
+	}
+
+	switch {
+	case k8serrors.IsNotFound(err):
+		return notFoundError(err)
+	case k8serrors.IsConflict(err):
+		return newError(ReasonConflict, err, "update conflict")
+	case k8serrors.IsForbidden(err), k8serrors.IsUnauthorized(err):
+		return newError(ReasonForbidden, err, "access denied")
+	case k8serrors.IsTimeout(err), k8serrors.IsServerTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return newError(ReasonTimeout, err, "request timed out")
+	case k8serrors.IsInvalid(err), k8serrors.IsBadRequest(err):
+		return newError(ReasonInvalidConfig, err, "request rejected by the API server")
+	}
+	return &PluginError{Reason: ReasonInternal, Message: err.Error()}
+}
+
+// notFoundError classifies a NotFound API error by the kind of the missing object, the
+// other objects the plugin reads being referred to by the plugin configuration.
+func notFoundError(err error) *PluginError {
+	var details *metav1.StatusDetails
+	var status k8serrors.APIStatus
+	if errors.As(err, &status) {
+		details = status.Status().Details
+	}
+	if details == nil {
+		return newError(ReasonInternal, err, "resource not found")
+	}
+	switch {
+	case details.Kind == "routes" && details.Group == routev1.GroupName:
+		return newError(ReasonRouteNotFound, err, "route not found")
+	case details.Kind == "services" && details.Group == "":
+		return newError(ReasonServiceMissing, err, "service not found")
+	}
+	return newError(ReasonInvalidConfig, err, "%s %q not found", details.Kind, details.Name)
+}
+
+// joinErrors combines the errors of several routes into one, keeping the reason
+// and hint of the first one so that the rendered prefix stays stable.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+
+	first := classifyError(errs[0])
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		pluginErr := classifyError(err)
+		msg := string(pluginErr.Reason) + ": " + pluginErr.Message
+		if pluginErr.Err != nil {
+			msg += ": " + pluginErr.Err.Error()
+		}
+		messages = append(messages, msg)
+	}
+	return &PluginError{
+		Reason:  first.Reason,
+		Message: fmt.Sprintf("%d routes failed: [%s]", len(errs), strings.Join(messages, "; ")),
+		Hint:    first.Hint,
+	}
+}
+
+// toRpcError converts err into the RpcError returned to the rollouts controller.
+func toRpcError(err error) pluginTypes.RpcError {
+	if err == nil {
+		return pluginTypes.RpcError{}
+	}
+	return pluginTypes.RpcError{ErrorString: classifyError(err).Error()}
+}
@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Test error taxonomy", func() {
+	routesResource := routev1.Resource("routes")
+
+	DescribeTable("classifyError maps API errors onto a reason",
+		func(err error, reason ErrorReason) {
+			Expect(classifyError(err).Reason).To(Equal(reason))
+			Expect(toRpcError(err).Error()).To(HavePrefix(string(reason) + ": "))
+		},
+		Entry("route not found", k8serrors.NewNotFound(routesResource, "route"), ReasonRouteNotFound),
+		Entry("service not found", k8serrors.NewNotFound(corev1.Resource("services"), "canary"), ReasonServiceMissing),
+		Entry("secret not found", k8serrors.NewNotFound(corev1.Resource("secrets"), "kubeconfig"), ReasonInvalidConfig),
+		Entry("rollout not found", fmt.Errorf("get rollout: %w", k8serrors.NewNotFound(v1alpha1.Resource("rollouts"), "demo")), ReasonInvalidConfig),
+		Entry("not found without details", &k8serrors.StatusError{ErrStatus: metav1.Status{Reason: metav1.StatusReasonNotFound}}, ReasonInternal),
+		Entry("conflict", k8serrors.NewConflict(routesResource, "route", errors.New("stale")), ReasonConflict),
+		Entry("forbidden", k8serrors.NewForbidden(routesResource, "route", errors.New("denied")), ReasonForbidden),
+		Entry("unauthorized", k8serrors.NewUnauthorized("who are you"), ReasonForbidden),
+		Entry("server timeout", k8serrors.NewServerTimeout(routesResource, "update", 1), ReasonTimeout),
+		Entry("context deadline", fmt.Errorf("get route: %w", context.DeadlineExceeded), ReasonTimeout),
+		Entry("bad request", k8serrors.NewBadRequest("bad"), ReasonInvalidConfig),
+		Entry("anything else", errors.New("boom"), ReasonInternal),
+	)
+
+	It("should keep the reason of a wrapped PluginError", func() {
+		err := fmt.Errorf("context: %w", newError(ReasonRouterRejected, nil, "rejected"))
+		Expect(ReasonOf(err)).To(Equal(ReasonRouterRejected))
+		Expect(classifyError(err).Reason).To(Equal(ReasonRouterRejected))
+	})
+
+	It("should render the reason, message, cause and hint", func() {
+		err := newError(ReasonConflict, errors.New("cause"), "route %q changed", "r")
+		Expect(err.Error()).To(Equal(`OpenshiftConflict: route "r" changed: cause (hint: ` + remediationHints[ReasonConflict] + ")"))
+	})
+
+	It("should return an empty RpcError for a nil error", func() {
+		Expect(toRpcError(nil).HasError()).To(BeFalse())
+	})
+})
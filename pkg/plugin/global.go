@@ -0,0 +1,314 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"log/slog"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// GlobalConfig is the plugin configuration file, holding the settings of the plugin
+// and the defaults of the configuration of the rollouts
+type GlobalConfig struct {
+	// PluginNames are the names the plugin is registered under in the rollouts controller besides
+	// argoproj-labs/openshift, looked up in order in the trafficRouting.plugins map of the rollouts
+	PluginNames []string `json:"pluginNames,omitempty"`
+	// RequestTimeout bounds the API calls made for each call of the rollouts controller, e.g. 30s, unbounded when empty
+	RequestTimeout metav1.Duration `json:"requestTimeout,omitempty"`
+	// AllowedNamespaces are the namespaces of the rollouts the plugin serves, every namespace when empty
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	// RouteNamespacePolicy restricts the namespaces of the routes the rollouts of each namespace may manage,
+	// every namespace being allowed when unset
+	RouteNamespacePolicy *RouteNamespacePolicy `json:"routeNamespacePolicy,omitempty"`
+	// KubeconfigSecretNamespaces maps the namespace of the rollouts, or * for every namespace, to the other
+	// namespaces whose kubeconfig Secrets they may read, * allowing every namespace. Rollouts only read the
+	// Secrets of their own namespace when unset.
+	KubeconfigSecretNamespaces map[string][]string `json:"kubeconfigSecretNamespaces,omitempty"`
+	// AllowedHostDomains maps the namespace of the rollouts, or * for every namespace, to the domains of the
+	// hosts of the routes the plugin creates for them from routeTemplate and previewRoute, * allowing every
+	// domain. Every host is allowed when unset.
+	AllowedHostDomains map[string][]string `json:"allowedHostDomains,omitempty"`
+	// AnnotationProfiles are named load-balancing settings that rollouts refer to with loadBalancing.profile
+	AnnotationProfiles map[string]LoadBalancing `json:"annotationProfiles,omitempty"`
+	// LogLevel is the level of the logs of the plugin, e.g. debug, info, warn or error, the level given with -l when empty
+	LogLevel *slog.Level `json:"logLevel,omitempty"`
+	// MetricsAddress is the address serving the Prometheus metrics of the plugin on /metrics, e.g. :9090.
+	// It is only read when the plugin starts.
+	MetricsAddress string `json:"metricsAddress,omitempty"`
+	// Defaults are used for the settings a rollout does not configure
+	Defaults RolloutDefaults `json:"defaults,omitempty"`
+}
+
+// RolloutDefaults are the settings of the plugin configuration of a rollout that can be defaulted.
+// A setting configured by the rollout replaces the default as a whole.
+type RolloutDefaults struct {
+	LoadBalancing     *LoadBalancing    `json:"loadBalancing,omitempty"`
+	RouterShards      []string          `json:"routerShards,omitempty"`
+	TLSPolicy         TLSPolicy         `json:"tlsPolicy,omitempty"`
+	WeightScale       int32             `json:"weightScale,omitempty"`
+	DriftPolicy       DriftPolicy       `json:"driftPolicy,omitempty"`
+	MaxCanaryWeight   int32             `json:"maxCanaryWeight,omitempty"`
+	MaxWeightIncrease int32             `json:"maxWeightIncrease,omitempty"`
+	WeightLimitPolicy WeightLimitPolicy `json:"weightLimitPolicy,omitempty"`
+}
+
+// LoadGlobalConfig reads and validates the plugin configuration file.
+func LoadGlobalConfig(path string) (*GlobalConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGlobalConfig(data)
+}
+
+// ParseGlobalConfig decodes and validates the YAML or JSON content of a plugin configuration file.
+func ParseGlobalConfig(data []byte) (*GlobalConfig, error) {
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, invalidGlobalConfigError(err)
+	}
+	config := &GlobalConfig{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return config, nil
+	}
+
+	errs := unknownFields(raw, reflect.TypeOf(GlobalConfig{}), nil)
+	if err := json.Unmarshal(raw, config); err != nil {
+		return nil, invalidGlobalConfigError(err)
+	}
+	errs = append(errs, validateGlobalConfig(config)...)
+	if len(errs) > 0 {
+		return nil, invalidGlobalConfigError(errs.ToAggregate())
+	}
+	return config, nil
+}
+
+func invalidGlobalConfigError(err error) error {
+	return &PluginError{
+		Reason:  ReasonInvalidConfig,
+		Message: "invalid plugin configuration file",
+		Hint:    "fix the file given to the plugin with -config",
+		Err:     err,
+	}
+}
+
+func validateGlobalConfig(config *GlobalConfig) field.ErrorList {
+	var errs field.ErrorList
+	names := map[string]bool{PluginName: true}
+	for i, name := range config.PluginNames {
+		namePath := field.NewPath("pluginNames").Index(i)
+		if name == "" {
+			errs = append(errs, field.Required(namePath, "the plugin name must not be empty"))
+		} else if names[name] {
+			errs = append(errs, field.Duplicate(namePath, name))
+		}
+		names[name] = true
+	}
+	if config.RequestTimeout.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("requestTimeout"), config.RequestTimeout.Duration.String(), "must not be negative"))
+	}
+	for i, namespace := range config.AllowedNamespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(field.NewPath("allowedNamespaces").Index(i), namespace, msg))
+		}
+	}
+	errs = append(errs, validateRouteNamespacePolicy(config.RouteNamespacePolicy, field.NewPath("routeNamespacePolicy"))...)
+	errs = append(errs, validateNamespaceMap(config.KubeconfigSecretNamespaces, field.NewPath("kubeconfigSecretNamespaces"))...)
+	errs = append(errs, validateHostDomains(config.AllowedHostDomains, field.NewPath("allowedHostDomains"))...)
+
+	profilesPath := field.NewPath("annotationProfiles")
+	for _, name := range sortedKeys(config.AnnotationProfiles) {
+		profile := config.AnnotationProfiles[name]
+		for _, msg := range validation.IsDNS1123Label(name) {
+			errs = append(errs, field.Invalid(profilesPath.Key(name), name, msg))
+		}
+		if profile.Profile != "" {
+			errs = append(errs, field.Forbidden(profilesPath.Key(name).Child("profile"), "profiles cannot refer to other profiles"))
+		}
+		errs = append(errs, validateLoadBalancing(&profile, profilesPath.Key(name))...)
+	}
+
+	if config.MetricsAddress != "" {
+		if _, _, err := net.SplitHostPort(config.MetricsAddress); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("metricsAddress"), config.MetricsAddress, err.Error()))
+		}
+	}
+
+	defaults := config.Defaults
+	defaultsPath := field.NewPath("defaults")
+	errs = append(errs, validateLoadBalancing(defaults.LoadBalancing, defaultsPath.Child("loadBalancing"))...)
+	if lb := defaults.LoadBalancing; lb != nil && lb.Profile != "" {
+		if _, ok := config.AnnotationProfiles[lb.Profile]; !ok {
+			errs = append(errs, field.NotFound(defaultsPath.Child("loadBalancing", "profile"), lb.Profile))
+		}
+	}
+	errs = append(errs, validateRouterShards(defaults.RouterShards, defaultsPath.Child("routerShards"))...)
+	errs = append(errs, validateTLSPolicy(defaults.TLSPolicy, defaultsPath.Child("tlsPolicy"))...)
+	errs = append(errs, validateWeightScale(defaults.WeightScale, defaultsPath.Child("weightScale"))...)
+	errs = append(errs, validateDriftPolicy(defaults.DriftPolicy, defaultsPath.Child("driftPolicy"))...)
+	errs = append(errs, validateWeightLimit(defaults.MaxCanaryWeight, defaultsPath.Child("maxCanaryWeight"))...)
+	errs = append(errs, validateWeightLimit(defaults.MaxWeightIncrease, defaultsPath.Child("maxWeightIncrease"))...)
+	errs = append(errs, validateWeightLimitPolicy(defaults.WeightLimitPolicy, defaultsPath.Child("weightLimitPolicy"))...)
+	return errs
+}
+
+// apply sets the defaults on the settings the rollout does not configure.
+func (d *RolloutDefaults) apply(openshift *OpenshiftTrafficRouting) {
+	if openshift.LoadBalancing == nil {
+		openshift.LoadBalancing = d.LoadBalancing
+	}
+	if len(openshift.RouterShards) == 0 {
+		openshift.RouterShards = d.RouterShards
+	}
+	if openshift.TLSPolicy == "" {
+		openshift.TLSPolicy = d.TLSPolicy
+	}
+	if openshift.WeightScale == 0 {
+		openshift.WeightScale = d.WeightScale
+	}
+	if openshift.DriftPolicy == "" {
+		openshift.DriftPolicy = d.DriftPolicy
+	}
+	if openshift.MaxCanaryWeight == 0 {
+		openshift.MaxCanaryWeight = d.MaxCanaryWeight
+	}
+	if openshift.MaxWeightIncrease == 0 {
+		openshift.MaxWeightIncrease = d.MaxWeightIncrease
+	}
+	if openshift.WeightLimitPolicy == "" {
+		openshift.WeightLimitPolicy = d.WeightLimitPolicy
+	}
+}
+
+// resolveProfile returns the load-balancing settings with those of their profile underneath.
+func resolveProfile(lb *LoadBalancing, profiles map[string]LoadBalancing) (*LoadBalancing, error) {
+	if lb == nil || lb.Profile == "" {
+		return lb, nil
+	}
+	profile, ok := profiles[lb.Profile]
+	if !ok {
+		return nil, &PluginError{
+			Reason:  ReasonInvalidConfig,
+			Message: "the annotation profile " + lb.Profile + " is not defined",
+			Hint:    "add the profile to annotationProfiles in the plugin configuration file or correct loadBalancing.profile",
+		}
+	}
+
+	resolved := profile
+	resolved.Profile = ""
+	resolved.Annotations = maps.Clone(profile.Annotations)
+	if len(lb.Annotations) > 0 {
+		if resolved.Annotations == nil {
+			resolved.Annotations = map[string]string{}
+		}
+		maps.Copy(resolved.Annotations, lb.Annotations)
+	}
+	if lb.Balance != "" {
+		resolved.Balance = lb.Balance
+	}
+	if lb.DisableCookies != nil {
+		resolved.DisableCookies = lb.DisableCookies
+	}
+	if lb.Timeout != "" {
+		resolved.Timeout = lb.Timeout
+	}
+	return &resolved, nil
+}
+
+// SetGlobalConfig replaces the plugin configuration file used by the next calls of the rollouts controller.
+func (r *RpcPlugin) SetGlobalConfig(config *GlobalConfig) {
+	r.global.Store(config)
+}
+
+// globalConfig returns the plugin configuration file, empty when none was set.
+func (r *RpcPlugin) globalConfig() *GlobalConfig {
+	if config := r.global.Load(); config != nil {
+		return config
+	}
+	return &GlobalConfig{}
+}
+
+// rolloutRouting returns the validated plugin configuration of the rollout, completed with the
+// defaults of the plugin configuration file.
+func (r *RpcPlugin) rolloutRouting(rollout *v1alpha1.Rollout) (*OpenshiftTrafficRouting, error) {
+	global := r.globalConfig()
+	openshift, pluginPath, errs := rolloutConfig(rollout, global.PluginNames)
+	if len(errs) > 0 {
+		return nil, invalidConfigError(errs)
+	}
+
+	if len(global.AllowedNamespaces) > 0 && !slices.Contains(global.AllowedNamespaces, rollout.Namespace) {
+		return nil, &PluginError{
+			Reason:  ReasonForbidden,
+			Message: "rollouts of namespace " + rollout.Namespace + " are not served by the plugin",
+			Hint:    "add the namespace to allowedNamespaces in the plugin configuration file",
+		}
+	}
+	global.Defaults.apply(openshift)
+	var err error
+	if openshift.LoadBalancing, err = resolveProfile(openshift.LoadBalancing, global.AnnotationProfiles); err != nil {
+		return nil, err
+	}
+
+	// the defaults may conflict with the settings of the rollout
+	if errs := validateConfig(openshift, pluginPath); len(errs) > 0 {
+		return nil, invalidConfigError(errs)
+	}
+	return openshift, nil
+}
+
+// requestContext returns the context of the API calls made for a call of the rollouts controller.
+func (r *RpcPlugin) requestContext() (context.Context, context.CancelFunc) {
+	if timeout := r.globalConfig().RequestTimeout.Duration; timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// WatchGlobalConfig polls the plugin configuration file every interval until ctx is done and calls apply
+// with its new content whenever it changes. Invalid contents are logged and leave the configuration unchanged.
+func WatchGlobalConfig(ctx context.Context, path string, interval time.Duration, apply func(*GlobalConfig)) {
+	last, _ := os.ReadFile(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Error("unable to read the plugin configuration file", slog.String("path", path), slog.Any("err", err))
+			continue
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+
+		config, err := ParseGlobalConfig(data)
+		if err != nil {
+			configReloads.WithLabelValues(resultFailure).Inc()
+			slog.Error("keeping the previous plugin configuration", slog.String("path", path), slog.Any("err", err))
+			continue
+		}
+		configReloads.WithLabelValues(resultSuccess).Inc()
+		slog.Info("reloaded the plugin configuration file", slog.String("path", path))
+		apply(config)
+	}
+}
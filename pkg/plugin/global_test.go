@@ -0,0 +1,230 @@
+package plugin
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const globalConfigFile = `
+pluginNames: [argoproj-labs/openshift-v2]
+requestTimeout: 30s
+allowedNamespaces: [default, apps]
+annotationProfiles:
+  sticky:
+    balance: source
+    annotations:
+      example.com/profile: sticky
+metricsAddress: ":9090"
+logLevel: debug
+defaults:
+  loadBalancing:
+    profile: sticky
+  tlsPolicy: Fail
+  weightScale: 256
+  driftPolicy: Warn
+`
+
+var _ = Describe("Test the plugin configuration file", func() {
+	It("should parse a valid file", func() {
+		config, err := ParseGlobalConfig([]byte(globalConfigFile))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.PluginNames).To(Equal([]string{"argoproj-labs/openshift-v2"}))
+		Expect(config.RequestTimeout.Duration).To(Equal(30 * time.Second))
+		Expect(config.AllowedNamespaces).To(Equal([]string{"default", "apps"}))
+		Expect(config.AnnotationProfiles).To(HaveKeyWithValue("sticky", LoadBalancing{
+			Balance:     "source",
+			Annotations: map[string]string{"example.com/profile": "sticky"},
+		}))
+		Expect(config.MetricsAddress).To(Equal(":9090"))
+		Expect(config.LogLevel).To(Equal(ptr.To(slog.LevelDebug)))
+		Expect(config.Defaults.WeightScale).To(Equal(int32(256)))
+	})
+
+	It("should accept an empty file", func() {
+		config, err := ParseGlobalConfig(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config).To(Equal(&GlobalConfig{}))
+	})
+
+	It("should report every problem with its field path", func() {
+		_, err := ParseGlobalConfig([]byte(`
+pluginNames: ["", argoproj-labs/openshift]
+requestTimeout: -1s
+allowedNamespaces: [Apps]
+allowedHostDomains:
+  apps: ["*", "Example_com"]
+annotationProfiles:
+  sticky:
+    profile: other
+    balance: sticky
+metricsAddress: "9090"
+defaults:
+  loadBalancing:
+    profile: missing
+  weightScale: 1000
+  driftPolicy: Overwrite
+  mode: affinity
+`))
+		Expect(ReasonOf(err)).To(Equal(ReasonInvalidConfig))
+		for _, msg := range []string{
+			`defaults.mode: Unsupported value: "mode"`,
+			"pluginNames[0]: Required value",
+			`pluginNames[1]: Duplicate value: "argoproj-labs/openshift"`,
+			`requestTimeout: Invalid value: "-1s": must not be negative`,
+			`allowedNamespaces[0]: Invalid value: "Apps"`,
+			`allowedHostDomains[apps][1]: Invalid value: "Example_com"`,
+			`annotationProfiles[sticky].profile: Forbidden`,
+			`annotationProfiles[sticky].balance: Unsupported value: "sticky"`,
+			`metricsAddress: Invalid value: "9090"`,
+			`defaults.loadBalancing.profile: Not found: "missing"`,
+			`defaults.weightScale: Invalid value: 1000: must be between 100 and 256`,
+			`defaults.driftPolicy: Unsupported value: "Overwrite"`,
+		} {
+			Expect(err.Error()).To(ContainSubstring(msg))
+		}
+	})
+
+	It("should report an unknown log level", func() {
+		_, err := ParseGlobalConfig([]byte("logLevel: verbose\n"))
+		Expect(err).To(MatchError(ContainSubstring(`level string "verbose": unknown name`)))
+	})
+
+	Describe("the defaults of the rollouts", func() {
+		var (
+			ctx         context.Context
+			routeClient *fake.Clientset
+			r           *RpcPlugin
+			rollout     *v1alpha1.Rollout
+		)
+
+		getRoute := func() *routev1.Route {
+			route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			return route
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+			r = &RpcPlugin{routeClient: routeClient}
+			config, err := ParseGlobalConfig([]byte(globalConfigFile))
+			Expect(err).ToNot(HaveOccurred())
+			r.SetGlobalConfig(config)
+			rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		})
+
+		It("should complete the configuration of the rollout", func() {
+			openshift, err := r.rolloutRouting(rollout)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(openshift.LoadBalancing).To(Equal(&LoadBalancing{
+				Balance:     "source",
+				Annotations: map[string]string{"example.com/profile": "sticky"},
+			}))
+			Expect(openshift.TLSPolicy).To(Equal(TLSPolicyFail))
+			Expect(openshift.WeightScale).To(Equal(int32(256)))
+			Expect(openshift.DriftPolicy).To(Equal(DriftPolicyWarn))
+		})
+
+		It("should let the rollout override the defaults and the profile", func() {
+			setPluginConfig(rollout, OpenshiftTrafficRouting{
+				Routes:        []string{mocks.RouteName},
+				LoadBalancing: &LoadBalancing{Profile: "sticky", Balance: "roundrobin", Annotations: map[string]string{"example.com/rollout": "set"}},
+				TLSPolicy:     TLSPolicyWarn,
+				WeightScale:   100,
+			})
+			openshift, err := r.rolloutRouting(rollout)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(openshift.LoadBalancing).To(Equal(&LoadBalancing{
+				Balance:     "roundrobin",
+				Annotations: map[string]string{"example.com/profile": "sticky", "example.com/rollout": "set"},
+			}))
+			Expect(openshift.TLSPolicy).To(Equal(TLSPolicyWarn))
+			Expect(openshift.WeightScale).To(Equal(int32(100)))
+			Expect(r.globalConfig().AnnotationProfiles["sticky"].Annotations).To(HaveLen(1))
+		})
+
+		It("should read the configuration under another name of the plugin", func() {
+			plugins := rollout.Spec.Strategy.Canary.TrafficRouting.Plugins
+			plugins["argoproj-labs/openshift-v2"] = plugins[PluginName]
+			delete(plugins, PluginName)
+			plugins["argoproj-labs/other"] = []byte(`{}`)
+			openshift, err := r.rolloutRouting(rollout)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(openshift.Routes).To(Equal([]string{mocks.RouteName}))
+		})
+
+		It("should report an undefined profile", func() {
+			setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, LoadBalancing: &LoadBalancing{Profile: "missing"}})
+			_, err := r.rolloutRouting(rollout)
+			Expect(ReasonOf(err)).To(Equal(ReasonInvalidConfig))
+			Expect(err.Error()).To(ContainSubstring("the annotation profile missing is not defined"))
+		})
+
+		It("should refuse rollouts of other namespaces", func() {
+			rollout.Namespace = "other"
+			rpcErr := r.SetWeight(rollout, 20, nil)
+			Expect(rpcErr.ErrorString).To(HavePrefix("OpenshiftForbidden: rollouts of namespace other are not served by the plugin"))
+		})
+
+		It("should write and verify weights at the configured scale", func() {
+			Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+			route := getRoute()
+			Expect(route.Spec.To.Weight).To(Equal(ptr.To[int32](179)))
+			Expect(route.Spec.AlternateBackends[0].Weight).To(Equal(ptr.To[int32](77)))
+			Expect(route.Annotations).To(HaveKeyWithValue("example.com/profile", "sticky"))
+
+			verified, rpcErr := r.VerifyWeight(rollout, 30, nil)
+			Expect(rpcErr.HasError()).To(BeFalse())
+			Expect(verified).To(Equal(pluginTypes.Verified))
+		})
+
+		It("should use the configuration set last", func() {
+			r.SetGlobalConfig(&GlobalConfig{})
+			Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+			Expect(getRoute().Spec.To.Weight).To(Equal(ptr.To[int32](70)))
+		})
+
+		It("should count the calls by result", func() {
+			before := testutil.ToFloat64(calls.WithLabelValues("SetWeight", string(ReasonForbidden)))
+			rollout.Namespace = "other"
+			r.SetWeight(rollout, 20, nil)
+			Expect(testutil.ToFloat64(calls.WithLabelValues("SetWeight", string(ReasonForbidden)))).To(Equal(before + 1))
+		})
+	})
+
+	It("should reload the file when it changes", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte("requestTimeout: 10s\n"), 0o600)).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		reloaded := make(chan *GlobalConfig, 2)
+		go WatchGlobalConfig(ctx, path, 10*time.Millisecond, func(config *GlobalConfig) { reloaded <- config })
+
+		Consistently(reloaded, 50*time.Millisecond).ShouldNot(Receive())
+
+		failures := testutil.ToFloat64(configReloads.WithLabelValues(resultFailure))
+		Expect(os.WriteFile(path, []byte("requestTimeout: soon\n"), 0o600)).To(Succeed())
+		Eventually(func() float64 { return testutil.ToFloat64(configReloads.WithLabelValues(resultFailure)) }).Should(Equal(failures + 1))
+		Expect(reloaded).ToNot(Receive())
+
+		Expect(os.WriteFile(path, []byte("requestTimeout: 20s\n"), 0o600)).To(Succeed())
+		var config *GlobalConfig
+		Eventually(reloaded).Should(Receive(&config))
+		Expect(config.RequestTimeout.Duration).To(Equal(20 * time.Second))
+	})
+})
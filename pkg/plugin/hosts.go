@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateHostDomains checks a map from the namespace of the rollouts to the domains of the hosts
+// they may give the routes the plugin creates, where * stands for every namespace or every domain.
+func validateHostDomains(allowed map[string][]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, namespace := range sortedKeys(allowed) {
+		if namespace != anyNamespace {
+			for _, msg := range validation.IsDNS1123Label(namespace) {
+				errs = append(errs, field.Invalid(path.Key(namespace), namespace, msg))
+			}
+		}
+		for i, domain := range allowed[namespace] {
+			if domain == anyNamespace {
+				continue
+			}
+			for _, msg := range validation.IsDNS1123Subdomain(domain) {
+				errs = append(errs, field.Invalid(path.Key(namespace).Index(i), domain, msg))
+			}
+		}
+	}
+	return errs
+}
+
+// hostAllowed tells whether host is one of the domains the map allows the rollouts of rolloutNamespace
+// to use, or a subdomain of one. An empty map allows every host.
+func hostAllowed(allowed map[string][]string, rolloutNamespace, host string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, key := range []string{rolloutNamespace, anyNamespace} {
+		for _, domain := range allowed[key] {
+			if domain == anyNamespace || host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkHost refuses a host outside the domains the plugin configuration file allows the rollout
+// to give the routes the plugin creates. setting names where the host comes from.
+func checkHost(allowed map[string][]string, rollout *v1alpha1.Rollout, setting, host string) error {
+	if host == "" || hostAllowed(allowed, rollout.Namespace, host) {
+		return nil
+	}
+	domains := slices.Concat(allowed[rollout.Namespace], allowed[anyNamespace])
+	return &PluginError{
+		Reason:  ReasonForbidden,
+		Message: "host " + host + " of the " + setting + " of rollout " + rolloutKey(rollout) + " is not in the allowed domains " + strings.Join(domains, ", "),
+		Hint:    "use a host of an allowed domain, or add its domain to allowedHostDomains[" + rollout.Namespace + "] in the plugin configuration file",
+	}
+}
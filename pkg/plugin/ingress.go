@@ -0,0 +1,35 @@
+package plugin
+
+import (
+	routev1 "github.com/openshift/api/route/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ingressOwner returns the Ingress the route was generated from, or nil.
+func ingressOwner(route *routev1.Route) *metav1.OwnerReference {
+	for i, owner := range route.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(owner.APIVersion)
+		if err != nil {
+			continue
+		}
+		if owner.Kind == "Ingress" && (gv.Group == "networking.k8s.io" || gv.Group == "extensions") {
+			return &route.OwnerReferences[i]
+		}
+	}
+	return nil
+}
+
+// checkIngressOwner refuses to modify a Route owned by an Ingress, whose changes the
+// ingress-to-route controller would silently revert.
+func checkIngressOwner(route *routev1.Route) error {
+	owner := ingressOwner(route)
+	if owner == nil {
+		return nil
+	}
+	return &PluginError{
+		Reason:  ReasonConflict,
+		Message: "route " + route.Namespace + "/" + route.Name + " is generated from Ingress " + owner.Name + " and changes to it would be reverted by the ingress-to-route controller",
+		Hint:    "reference a Route that is not generated from an Ingress",
+	}
+}
@@ -0,0 +1,55 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Test routes owned by an Ingress", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		route.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       "Ingress",
+			Name:       "frontend",
+		}}
+		_, err = routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should refuse to update the route", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.Error()).To(HavePrefix(string(ReasonConflict) + ": route default/argo-rollouts is generated from Ingress frontend"))
+
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(route.Spec.To.Weight).To(HaveValue(Equal(mocks.RouteDesiredWeight)))
+	})
+
+	It("should not fail RemoveManagedRoutes when there is nothing to restore", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+	})
+
+	It("should update other routes as usual", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.ValidRouteName)
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+	})
+})
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// WeightLimitPolicy selects what the plugin does with a weight above maxCanaryWeight
+type WeightLimitPolicy string
+
+const (
+	// WeightLimitFail refuses the weight, leaving the routes unchanged
+	WeightLimitFail WeightLimitPolicy = "Fail"
+	// WeightLimitClamp sends maxCanaryWeight to the canary instead
+	WeightLimitClamp WeightLimitPolicy = "Clamp"
+)
+
+func validateWeightLimitPolicy(policy WeightLimitPolicy, path *field.Path) field.ErrorList {
+	policies := []string{string(WeightLimitFail), string(WeightLimitClamp)}
+	if policy != "" && !slices.Contains(policies, string(policy)) {
+		return field.ErrorList{field.NotSupported(path, policy, policies)}
+	}
+	return nil
+}
+
+// validateWeightLimit checks maxCanaryWeight or maxWeightIncrease, 0 leaving the weight unlimited.
+func validateWeightLimit(limit int32, path *field.Path) field.ErrorList {
+	if limit < 0 || limit > 100 {
+		return field.ErrorList{field.Invalid(path, limit, "must be between 0 and 100, 0 leaving the weight unlimited")}
+	}
+	return nil
+}
+
+// limitWeight returns the weight to send to the canary once maxCanaryWeight and maxWeightIncrease
+// are applied to the weight requested by a canary step. Going back to the stable service and the
+// promotion that follows the last step are never limited.
+func (o *OpenshiftTrafficRouting) limitWeight(rollout *v1alpha1.Rollout, desiredWeight int32) (int32, error) {
+	if desiredWeight == 0 || promoting(rollout) {
+		return desiredWeight, nil
+	}
+
+	if o.MaxCanaryWeight > 0 && desiredWeight > o.MaxCanaryWeight {
+		if o.WeightLimitPolicy == WeightLimitClamp {
+			slog.Warn("limiting the canary weight to maxCanaryWeight", slog.String("rollout", rolloutKey(rollout)),
+				slog.Any("desiredWeight", desiredWeight), slog.Any("maxCanaryWeight", o.MaxCanaryWeight))
+			desiredWeight = o.MaxCanaryWeight
+		} else {
+			return 0, &PluginError{
+				Reason:  ReasonWeightLimit,
+				Message: fmt.Sprintf("weight %d of rollout %s exceeds maxCanaryWeight %d", desiredWeight, rolloutKey(rollout), o.MaxCanaryWeight),
+				Hint:    remediationHints[ReasonWeightLimit],
+			}
+		}
+	}
+
+	current := currentWeight(rollout)
+	if o.MaxWeightIncrease > 0 && desiredWeight-current > o.MaxWeightIncrease {
+		return 0, &PluginError{
+			Reason: ReasonWeightLimit,
+			Message: fmt.Sprintf("weight %d of rollout %s increases the canary weight by %d from %d, more than maxWeightIncrease %d",
+				desiredWeight, rolloutKey(rollout), desiredWeight-current, current, o.MaxWeightIncrease),
+			Hint: remediationHints[ReasonWeightLimit],
+		}
+	}
+	return desiredWeight, nil
+}
+
+// currentWeight returns the canary weight the controller recorded in the status of the rollout.
+// With the Clamp policy, it is the weight of the step rather than the clamped weight the routes got,
+// so that raising maxCanaryWeight during the canary lets the next step go beyond maxWeightIncrease.
+func currentWeight(rollout *v1alpha1.Rollout) int32 {
+	if canary := rollout.Status.Canary; canary.Weights != nil {
+		return canary.Weights.Canary.Weight
+	}
+	return 0
+}
+
+// promoting tells whether the controller is promoting the canary, after its last step or
+// when asked for a full promotion, and sends it all the traffic before it becomes stable.
+func promoting(rollout *v1alpha1.Rollout) bool {
+	if rollout.Status.PromoteFull {
+		return true
+	}
+	steps := len(rollout.Spec.Strategy.Canary.Steps)
+	index := rollout.Status.CurrentStepIndex
+	if index == nil {
+		return steps == 0
+	}
+	return int(*index) >= steps
+}
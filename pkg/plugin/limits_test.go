@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Test the weight limits", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	getRoute := func() *routev1.Route {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return route
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.Spec.Strategy.Canary.Steps = []v1alpha1.CanaryStep{{SetWeight: ptr.To[int32](10)}, {SetWeight: ptr.To[int32](100)}}
+		rollout.Status.CurrentStepIndex = ptr.To[int32](1)
+		rollout.Status.Canary.Weights = &v1alpha1.TrafficWeights{Canary: v1alpha1.WeightDestination{Weight: 10}}
+	})
+
+	It("should refuse a weight above maxCanaryWeight", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, MaxCanaryWeight: 50})
+		rpcErr := r.SetWeight(rollout, 100, nil)
+		Expect(rpcErr.ErrorString).To(HavePrefix("OpenshiftWeightLimitExceeded: weight 100 of rollout default/" + rollout.Name +
+			" exceeds maxCanaryWeight 50 (hint: correct the setWeight steps of the rollout"))
+		Expect(getRoute().Spec.To.Weight).To(HaveValue(Equal(mocks.RouteDesiredWeight)))
+	})
+
+	It("should clamp a weight above maxCanaryWeight with the Clamp policy", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, MaxCanaryWeight: 50, WeightLimitPolicy: WeightLimitClamp})
+		Expect(r.SetWeight(rollout, 100, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends[0].Weight).To(Equal(ptr.To[int32](50)))
+
+		verified, rpcErr := r.VerifyWeight(rollout, 100, nil)
+		Expect(rpcErr.HasError()).To(BeFalse())
+		Expect(verified).To(Equal(pluginTypes.Verified))
+	})
+
+	It("should refuse an increase above maxWeightIncrease", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, MaxWeightIncrease: 20})
+		rpcErr := r.SetWeight(rollout, 40, nil)
+		Expect(rpcErr.ErrorString).To(HavePrefix("OpenshiftWeightLimitExceeded: weight 40 of rollout default/" + rollout.Name +
+			" increases the canary weight by 30 from 10, more than maxWeightIncrease 20"))
+
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends[0].Weight).To(Equal(ptr.To[int32](30)))
+	})
+
+	It("should always allow going back to the stable service", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, MaxCanaryWeight: 5, MaxWeightIncrease: 1})
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends).To(BeEmpty())
+	})
+
+	DescribeTable("should not limit the promotion",
+		func(update func(*v1alpha1.Rollout)) {
+			setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, MaxCanaryWeight: 50, MaxWeightIncrease: 20})
+			update(rollout)
+			Expect(r.SetWeight(rollout, 100, nil).HasError()).To(BeFalse())
+			Expect(getRoute().Spec.AlternateBackends[0].Weight).To(Equal(ptr.To[int32](100)))
+		},
+		Entry("after the last step", func(rollout *v1alpha1.Rollout) { rollout.Status.CurrentStepIndex = ptr.To[int32](2) }),
+		Entry("on a full promotion", func(rollout *v1alpha1.Rollout) { rollout.Status.PromoteFull = true }),
+		Entry("without steps", func(rollout *v1alpha1.Rollout) {
+			rollout.Spec.Strategy.Canary.Steps = nil
+			rollout.Status.CurrentStepIndex = nil
+		}),
+	)
+
+	It("should take the limits from the plugin configuration file", func() {
+		config, err := ParseGlobalConfig([]byte("defaults:\n  maxCanaryWeight: 50\n  weightLimitPolicy: Clamp\n"))
+		Expect(err).ToNot(HaveOccurred())
+		r.SetGlobalConfig(config)
+		Expect(r.SetWeight(rollout, 100, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends[0].Weight).To(Equal(ptr.To[int32](50)))
+	})
+
+	It("should validate the limits", func() {
+		_, errs := parseConfig([]byte(`{"routes":["r"],"maxCanaryWeight":101,"maxWeightIncrease":-1,"weightLimitPolicy":"Block"}`), field.NewPath("plugin"))
+		Expect(errs.ToAggregate().Error()).To(And(
+			ContainSubstring("plugin.maxCanaryWeight: Invalid value: 101: must be between 0 and 100, 0 leaving the weight unlimited"),
+			ContainSubstring("plugin.maxWeightIncrease: Invalid value: -1: must be between 0 and 100, 0 leaving the weight unlimited"),
+			ContainSubstring(`plugin.weightLimitPolicy: Unsupported value: "Block"`),
+		))
+	})
+})
@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"net/http"
+	"strings"
+
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// results of the calls and of the reloads of the plugin configuration file
+const (
+	resultSuccess = "Success"
+	resultFailure = "Failure"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	calls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rollouts_plugin_trafficrouter_openshift_calls_total",
+		Help: "Calls of the rollouts controller to the plugin by method and result, Success or the reason of the error.",
+	}, []string{"method", "result"})
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rollouts_plugin_trafficrouter_openshift_config_reloads_total",
+		Help: "Reloads of the plugin configuration file by result, Success or Failure.",
+	}, []string{"result"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(calls, configReloads)
+}
+
+// MetricsHandler serves the Prometheus metrics of the plugin.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// observeCall counts a call of the rollouts controller by its result.
+func observeCall(method string, rpcErr pluginTypes.RpcError) {
+	result := resultSuccess
+	if rpcErr.HasError() {
+		result = string(rpcErrorReason(rpcErr))
+	}
+	calls.WithLabelValues(method, result).Inc()
+}
+
+// rpcErrorReason returns the reason an RpcError starts with.
+func rpcErrorReason(rpcErr pluginTypes.RpcError) ErrorReason {
+	reason, _, _ := strings.Cut(rpcErr.ErrorString, ":")
+	return ErrorReason(reason)
+}
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// allowedRolloutNamespacesAnnotation lists, comma-separated, the namespaces of the rollouts a Route accepts
+// to be managed by when the policy lets routes opt in, * accepting every namespace
+const allowedRolloutNamespacesAnnotation = annotationPrefix + "allowed-rollout-namespaces"
+
+// anyNamespace stands for every namespace in the namespace maps of the plugin configuration file
+const anyNamespace = "*"
+
+// RouteNamespacePolicy restricts the namespaces of the routes the rollouts of each namespace may manage.
+// Routes in the namespace of the rollout are always allowed.
+type RouteNamespacePolicy struct {
+	// Allowed maps the namespace of the rollouts, or * for every namespace, to the other namespaces
+	// whose routes they may manage, * allowing every namespace
+	Allowed map[string][]string `json:"allowed,omitempty"`
+	// AllowRouteOptIn lets a Route of another namespace accept the rollouts of the namespaces listed in
+	// its trafficrouter-openshift.argoproj-labs.io/allowed-rollout-namespaces annotation
+	AllowRouteOptIn bool `json:"allowRouteOptIn,omitempty"`
+}
+
+func validateRouteNamespacePolicy(policy *RouteNamespacePolicy, path *field.Path) field.ErrorList {
+	if policy == nil {
+		return nil
+	}
+	return validateNamespaceMap(policy.Allowed, path.Child("allowed"))
+}
+
+// validateNamespaceMap checks a map from the namespace of the rollouts to the namespaces they may refer to,
+// where * stands for every namespace.
+func validateNamespaceMap(allowed map[string][]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, namespace := range sortedKeys(allowed) {
+		if namespace != anyNamespace {
+			for _, msg := range validation.IsDNS1123Label(namespace) {
+				errs = append(errs, field.Invalid(path.Key(namespace), namespace, msg))
+			}
+		}
+		for i, target := range allowed[namespace] {
+			if target == anyNamespace {
+				continue
+			}
+			for _, msg := range validation.IsDNS1123Label(target) {
+				errs = append(errs, field.Invalid(path.Key(namespace).Index(i), target, msg))
+			}
+		}
+	}
+	return errs
+}
+
+// namespaceAllowed tells whether the map allows the rollouts of rolloutNamespace to refer to the objects of namespace.
+func namespaceAllowed(allowed map[string][]string, rolloutNamespace, namespace string) bool {
+	if namespace == rolloutNamespace {
+		return true
+	}
+	for _, key := range []string{rolloutNamespace, anyNamespace} {
+		targets := allowed[key]
+		if slices.Contains(targets, namespace) || slices.Contains(targets, anyNamespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows tells whether the rollouts of rolloutNamespace may manage the routes of namespace,
+// without taking the opt-in of routes into account. A nil policy allows every namespace.
+func (p *RouteNamespacePolicy) allows(rolloutNamespace, namespace string) bool {
+	return p == nil || namespaceAllowed(p.Allowed, rolloutNamespace, namespace)
+}
+
+// optedIn tells whether the route accepts the rollouts of rolloutNamespace through its annotation.
+func (p *RouteNamespacePolicy) optedIn(rolloutNamespace string, route *routev1.Route) bool {
+	if p == nil || !p.AllowRouteOptIn || route == nil {
+		return false
+	}
+	for _, namespace := range strings.Split(route.Annotations[allowedRolloutNamespacesAnnotation], ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == rolloutNamespace || namespace == anyNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRoute refuses a route of the local cluster the rollout may not manage. The route is nil
+// when it does not exist yet, in which case it cannot opt in.
+func (p *RouteNamespacePolicy) checkRoute(rollout *v1alpha1.Rollout, namespace, name string, route *routev1.Route) error {
+	if p.allows(rollout.Namespace, namespace) || p.optedIn(rollout.Namespace, route) {
+		return nil
+	}
+	hint := "add " + namespace + " to routeNamespacePolicy.allowed[" + rollout.Namespace + "] in the plugin configuration file"
+	if p.AllowRouteOptIn {
+		hint += ", or add " + rollout.Namespace + " to the " + allowedRolloutNamespacesAnnotation + " annotation of the route"
+	}
+	return &PluginError{
+		Reason:  ReasonForbidden,
+		Message: "the route namespace policy does not allow rollout " + rolloutKey(rollout) + " to manage route " + namespace + "/" + name,
+		Hint:    hint,
+	}
+}
+
+// checkKubeconfigSecret refuses a kubeconfig Secret outside the namespace of the rollout that the
+// plugin configuration file does not explicitly allow it to read.
+func checkKubeconfigSecret(allowed map[string][]string, rollout *v1alpha1.Rollout, namespace, name string) error {
+	if namespaceAllowed(allowed, rollout.Namespace, namespace) {
+		return nil
+	}
+	return &PluginError{
+		Reason:  ReasonForbidden,
+		Message: "rollout " + rolloutKey(rollout) + " is not allowed to read the kubeconfig Secret " + namespace + "/" + name,
+		Hint:    "move the Secret to the namespace of the rollout, or add " + namespace + " to kubeconfigSecretNamespaces[" + rollout.Namespace + "] in the plugin configuration file",
+	}
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Test the route namespace policy", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	getRoute := func() *routev1.Route {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return route
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.Namespace = "apps"
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.Namespace + "/" + mocks.RouteName}})
+	})
+
+	It("should allow every namespace without a policy", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends).To(HaveExactElements(HaveField("Name", mocks.CanaryServiceName)))
+	})
+
+	It("should refuse routes of namespaces the policy does not allow", func() {
+		r.SetGlobalConfig(&GlobalConfig{RouteNamespacePolicy: &RouteNamespacePolicy{Allowed: map[string][]string{"apps": {"shared"}}}})
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.ErrorString).To(HavePrefix("OpenshiftForbidden: the route namespace policy does not allow rollout apps/" +
+			rollout.Name + " to manage route default/argo-rollouts"))
+		Expect(rpcErr.ErrorString).To(ContainSubstring("hint: add default to routeNamespacePolicy.allowed[apps]"))
+		Expect(getRoute().Spec.To.Weight).To(HaveValue(Equal(mocks.RouteDesiredWeight)))
+	})
+
+	It("should refuse restoring routes and deleting preview routes of namespaces the policy does not allow", func() {
+		rollout.UID = "rollout-uid"
+		rollout.Status.StableRS = "stable-hash"
+		rollout.Status.CurrentPodHash = "canary-hash"
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:       []string{mocks.Namespace + "/" + mocks.RouteName},
+			PreviewRoute: &PreviewRoute{Host: "preview.example.com"},
+		})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		route := getRoute()
+
+		r.SetGlobalConfig(&GlobalConfig{RouteNamespacePolicy: &RouteNamespacePolicy{Allowed: map[string][]string{"apps": {"shared"}}}})
+		rollout.Status.Abort = true
+		rpcErr := r.RemoveManagedRoutes(rollout)
+		Expect(rpcErr.ErrorString).To(HavePrefix("OpenshiftForbidden: 2 routes failed"))
+		Expect(rpcErr.ErrorString).To(ContainSubstring("to manage route default/argo-rollouts;"))
+		Expect(rpcErr.ErrorString).To(ContainSubstring("to manage route default/argo-rollouts-preview]"))
+		Expect(getRoute()).To(Equal(route))
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName+"-preview", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	DescribeTable("should allow the namespaces the policy lists",
+		func(allowed map[string][]string) {
+			r.SetGlobalConfig(&GlobalConfig{RouteNamespacePolicy: &RouteNamespacePolicy{Allowed: allowed}})
+			Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		},
+		Entry("namespace of the rollout", map[string][]string{"apps": {mocks.Namespace}}),
+		Entry("every namespace of the rollout", map[string][]string{"apps": {"*"}}),
+		Entry("every rollout namespace", map[string][]string{"*": {mocks.Namespace}}),
+	)
+
+	It("should always allow the namespace of the rollout", func() {
+		r.SetGlobalConfig(&GlobalConfig{RouteNamespacePolicy: &RouteNamespacePolicy{}})
+		rollout.Namespace = mocks.Namespace
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+	})
+
+	It("should let routes opt in when the policy allows it", func() {
+		route := getRoute()
+		route.Annotations = map[string]string{allowedRolloutNamespacesAnnotation: "team-a, apps"}
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		r.SetGlobalConfig(&GlobalConfig{RouteNamespacePolicy: &RouteNamespacePolicy{}})
+		Expect(r.SetWeight(rollout, 30, nil).ErrorString).To(HavePrefix(string(ReasonForbidden)))
+		Expect(getRoute().Spec.To.Weight).To(HaveValue(Equal(mocks.RouteDesiredWeight)))
+
+		r.SetGlobalConfig(&GlobalConfig{RouteNamespacePolicy: &RouteNamespacePolicy{AllowRouteOptIn: true}})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Spec.AlternateBackends).To(HaveExactElements(HaveField("Name", mocks.CanaryServiceName)))
+	})
+
+	It("should only read kubeconfig Secrets of other namespaces when the configuration file allows it", func() {
+		r.kubeClient = k8sfake.NewSimpleClientset()
+		setPluginConfig(rollout, OpenshiftTrafficRouting{ClusterRoutes: []ClusterRoutes{{
+			Cluster:          "edge",
+			KubeconfigSecret: SecretKeyReference{Namespace: "argo-rollouts", Name: "edge"},
+			Routes:           []string{mocks.RouteName},
+		}}})
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.ErrorString).To(HavePrefix(`OpenshiftForbidden: cluster "edge": rollout apps/` + rollout.Name +
+			" is not allowed to read the kubeconfig Secret argo-rollouts/edge"))
+
+		r.SetGlobalConfig(&GlobalConfig{KubeconfigSecretNamespaces: map[string][]string{"apps": {"argo-rollouts"}}})
+		rpcErr = r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.ErrorString).To(ContainSubstring(`kubeconfig Secret "edge" not found in namespace "argo-rollouts"`))
+	})
+
+	It("should validate the namespaces of the policy", func() {
+		_, err := ParseGlobalConfig([]byte(`
+routeNamespacePolicy:
+  allowed:
+    Apps: [shared]
+    team-a: ["*", "Shared"]
+`))
+		Expect(ReasonOf(err)).To(Equal(ReasonInvalidConfig))
+		Expect(err.Error()).To(ContainSubstring(`routeNamespacePolicy.allowed[Apps]: Invalid value: "Apps"`))
+		Expect(err.Error()).To(ContainSubstring(`routeNamespacePolicy.allowed[team-a][1]: Invalid value: "Shared"`))
+	})
+})
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"context"
+	"maps"
+
+	"log/slog"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+// Labels set on every object the plugin creates
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "rollouts-plugin-trafficrouter-openshift"
+
+	// the rollout owning the object, which owner references only record within its namespace
+	rolloutNamespaceLabel = annotationPrefix + "rollout-namespace"
+	rolloutNameLabel      = annotationPrefix + "rollout-name"
+	rolloutUIDLabel       = annotationPrefix + "rollout-uid"
+)
+
+// setOwner marks obj as created by the plugin for the rollout. Kubernetes garbage collects it
+// with the rollout when both live in the same namespace, the orphan sweeper does otherwise when enabled.
+func setOwner(obj metav1.Object, rollout *v1alpha1.Rollout) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[managedByLabel] = managedByValue
+	labels[rolloutNamespaceLabel] = rollout.Namespace
+	labels[rolloutNameLabel] = rollout.Name
+	if rollout.UID != "" {
+		labels[rolloutUIDLabel] = string(rollout.UID)
+	}
+	obj.SetLabels(labels)
+
+	if obj.GetNamespace() != rollout.Namespace || rollout.UID == "" {
+		return
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == rollout.UID {
+			return
+		}
+	}
+	// blockOwnerDeletion is left unset, it would require the permission to update the rollout's finalizers
+	obj.SetOwnerReferences(append(obj.GetOwnerReferences(), metav1.OwnerReference{
+		APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		Kind:       "Rollout",
+		Name:       rollout.Name,
+		UID:        rollout.UID,
+		Controller: ptr.To(true),
+	}))
+}
+
+// ownedBy tells whether obj was created by the plugin for the rollout.
+func ownedBy(obj metav1.Object, rollout *v1alpha1.Rollout) bool {
+	labels := obj.GetLabels()
+	return labels[managedByLabel] == managedByValue &&
+		labels[rolloutNamespaceLabel] == rollout.Namespace &&
+		labels[rolloutNameLabel] == rollout.Name
+}
+
+// withoutOwnerLabels returns a copy of labels without the labels setOwner sets, for objects cloned
+// from one the plugin may have created.
+func withoutOwnerLabels(labels map[string]string) map[string]string {
+	labels = maps.Clone(labels)
+	for _, label := range []string{managedByLabel, rolloutNamespaceLabel, rolloutNameLabel, rolloutUIDLabel} {
+		delete(labels, label)
+	}
+	return labels
+}
+
+// managedSelector selects the objects created by the plugin.
+func managedSelector() string {
+	return managedByLabel + "=" + managedByValue
+}
+
+// sweepOrphans deletes the routes the plugin created for rollouts that no longer exist,
+// which garbage collection misses when the route is in another namespace than its rollout.
+// A route whose rollout was deleted and created again under the same name is left to the new rollout.
+func (r *RpcPlugin) sweepOrphans(ctx context.Context) error {
+	routes, err := r.routeClient.RouteV1().Routes(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: managedSelector()})
+	if err != nil {
+		return err
+	}
+
+	for _, route := range routes.Items {
+		namespace, name := route.Labels[rolloutNamespaceLabel], route.Labels[rolloutNameLabel]
+		if namespace == "" || name == "" {
+			continue
+		}
+		rollout, err := r.rolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			if uid := route.Labels[rolloutUIDLabel]; uid != "" && types.UID(uid) != rollout.UID {
+				slog.Warn("not deleting a route created for a previous rollout of the same name, the current rollout manages it",
+					slog.String("route", route.Namespace+"/"+route.Name), slog.String("rollout", namespace+"/"+name))
+			}
+			continue
+		case k8serrors.IsNotFound(err):
+		default:
+			slog.Warn("unable to check the rollout of a plugin route", slog.String("route", route.Namespace+"/"+route.Name),
+				slog.String("rollout", namespace+"/"+name), slog.Any("err", err))
+			continue
+		}
+
+		slog.Info("deleting route left over by a deleted rollout", slog.String("route", route.Namespace+"/"+route.Name),
+			slog.String("rollout", namespace+"/"+name))
+		err = r.routeClient.RouteV1().Routes(route.Namespace).Delete(ctx, route.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &route.UID},
+		})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			slog.Warn("failed to delete a route left over by a deleted rollout", slog.String("route", route.Namespace+"/"+route.Name), slog.Any("err", err))
+		}
+	}
+	return nil
+}
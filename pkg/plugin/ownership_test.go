@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Test the ownership of plugin objects", func() {
+	var rollout *v1alpha1.Rollout
+
+	BeforeEach(func() {
+		rollout = &v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: mocks.Namespace, UID: "rollout-uid"}}
+	})
+
+	It("should label objects and own those of the rollout's namespace", func() {
+		route := &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: mocks.Namespace, Labels: map[string]string{"type": "public"}}}
+		setOwner(route, rollout)
+		setOwner(route, rollout)
+
+		Expect(route.Labels).To(Equal(map[string]string{
+			"type":                "public",
+			managedByLabel:        managedByValue,
+			rolloutNamespaceLabel: mocks.Namespace,
+			rolloutNameLabel:      "rollout",
+			rolloutUIDLabel:       "rollout-uid",
+		}))
+		Expect(route.OwnerReferences).To(HaveLen(1))
+		Expect(route.OwnerReferences[0].APIVersion).To(Equal("argoproj.io/v1alpha1"))
+		Expect(*route.OwnerReferences[0].Controller).To(BeTrue())
+		Expect(route.OwnerReferences[0].BlockOwnerDeletion).To(BeNil())
+		Expect(ownedBy(route, rollout)).To(BeTrue())
+
+		other := rollout.DeepCopy()
+		other.Name = "other"
+		Expect(ownedBy(route, other)).To(BeFalse())
+	})
+
+	It("should not own objects in other namespaces", func() {
+		route := &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "shared"}}
+		setOwner(route, rollout)
+		Expect(route.OwnerReferences).To(BeEmpty())
+		Expect(ownedBy(route, rollout)).To(BeTrue())
+	})
+
+	Describe("the orphan sweeper", func() {
+		var (
+			ctx         context.Context
+			routeClient *fake.Clientset
+			r           *RpcPlugin
+		)
+
+		managedRoute := func(namespace, name string, owner *v1alpha1.Rollout) *routev1.Route {
+			route := &routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+			setOwner(route, owner)
+			return route
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			deleted := &v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: mocks.Namespace, UID: "deleted-uid"}}
+			recreated := &v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: mocks.Namespace, UID: "old-uid"}}
+
+			routeClient = fake.NewSimpleClientset(
+				managedRoute("shared", "live", rollout),
+				managedRoute("shared", "orphan", deleted),
+				managedRoute("shared", "stale", recreated),
+				&routev1.Route{ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "shared"}},
+			)
+			r = &RpcPlugin{routeClient: routeClient, rolloutClient: rolloutfake.NewSimpleClientset(rollout)}
+		})
+
+		It("should delete the routes of deleted rollouts only", func() {
+			Expect(r.sweepOrphans(ctx)).To(Succeed())
+
+			for _, name := range []string{"live", "stale", "unmanaged"} {
+				_, err := routeClient.RouteV1().Routes("shared").Get(ctx, name, metav1.GetOptions{})
+				Expect(err).ToNot(HaveOccurred(), name)
+			}
+			_, err := routeClient.RouteV1().Routes("shared").Get(ctx, "orphan", metav1.GetOptions{})
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should need cluster-wide access to routes and rollouts", func() {
+			Expect(r.requiredPermissions()).ToNot(ContainElement(HaveField("Resource", "rollouts")))
+			r.SweepOrphanRoutes = true
+			Expect(r.requiredPermissions()).To(ContainElement(permission{Group: "argoproj.io", Resource: "rollouts", Verbs: []string{"get"}}))
+		})
+	})
+})
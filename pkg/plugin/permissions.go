@@ -0,0 +1,456 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// permission describes an access the plugin needs: verbs on a resource, or on a subresource
+// given as <resource>/<subresource>, restricted to ResourceNames when set. The permission
+// is cluster-wide when Namespace is empty.
+type permission struct {
+	Namespace     string
+	Group         string
+	Resource      string
+	Verbs         []string
+	ResourceNames []string
+}
+
+// rule returns the RBAC rule granting the permission.
+func (p permission) rule() rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{
+		APIGroups:     []string{p.Group},
+		Resources:     []string{p.Resource},
+		Verbs:         p.Verbs,
+		ResourceNames: p.ResourceNames,
+	}
+}
+
+// describe returns the access to one verb and name of the permission, e.g.
+// `update routes.route.openshift.io "a" in namespace apps`.
+func (p permission) describe(verb, name string) string {
+	resource, subresource, _ := strings.Cut(p.Resource, "/")
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if subresource != "" {
+		resource += "/" + subresource
+	}
+	access := verb + " " + resource
+	if name != "" {
+		access += fmt.Sprintf(" %q", name)
+	}
+	if p.Namespace == "" {
+		return access + " cluster-wide"
+	}
+	return access + " in namespace " + p.Namespace
+}
+
+// sweeperPermissions cover the cluster-wide access of the orphan sweeper
+var sweeperPermissions = []permission{
+	{Group: routev1.GroupName, Resource: "routes", Verbs: []string{"list", "delete"}},
+	{Group: v1alpha1.SchemeGroupVersion.Group, Resource: "rollouts", Verbs: []string{"get"}},
+}
+
+// requiredPermissions returns the cluster-wide permissions the plugin needs, checked by InitPlugin.
+// The permissions the routes of a rollout need are derived from the rollout, see verifyServedRollouts.
+func (r *RpcPlugin) requiredPermissions() []permission {
+	if r.SweepOrphanRoutes {
+		return sweeperPermissions
+	}
+	return nil
+}
+
+// SweeperPolicyRules returns the cluster-wide RBAC rules the orphan sweeper needs.
+func SweeperPolicyRules() []rbacv1.PolicyRule {
+	rules := make([]rbacv1.PolicyRule, 0, len(sweeperPermissions))
+	for _, p := range sweeperPermissions {
+		rules = append(rules, p.rule())
+	}
+	return rules
+}
+
+// PolicyTarget identifies the rollout AddPolicyRules generates the rules for
+type PolicyTarget struct {
+	// Namespace of the rollout
+	Namespace string
+	// CanaryService is the name of the canary Service of the rollout, needed with verifyCanaryCertificate
+	CanaryService string
+	// ServingCertSecrets maps the namespaces of the routes to the Secret holding the serving certificate
+	// of the canary Service in that namespace, needed with verifyCanaryCertificate
+	ServingCertSecrets map[string]string
+}
+
+// AddPolicyRules adds to rules, per namespace, the least-privilege RBAC rules the plugin
+// needs to manage the rollout with the given configuration.
+func AddPolicyRules(rules map[string][]rbacv1.PolicyRule, openshift *OpenshiftTrafficRouting, target PolicyTarget) error {
+	permissions, err := rolloutPermissions(openshift, target)
+	if err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		rules[p.Namespace] = addRule(rules[p.Namespace], p.rule())
+	}
+	return nil
+}
+
+// rolloutPermissions returns the permissions the plugin needs to manage the rollout with the given
+// configuration, the table both AddPolicyRules and the checks of the plugin derive from. When the
+// name of the canary Service or of its serving certificate Secret is unknown, it returns the other
+// permissions along with an error.
+func rolloutPermissions(openshift *OpenshiftTrafficRouting, target PolicyTarget) ([]permission, error) {
+	var permissions []permission
+	var errs []error
+	rolloutNamespace := target.Namespace
+	for _, route := range openshift.Routes {
+		namespace, name := splitRouteReference(route, rolloutNamespace)
+		permissions = append(permissions, permission{
+			Namespace:     namespace,
+			Group:         routev1.GroupName,
+			Resource:      "routes",
+			Verbs:         []string{"get", "update"},
+			ResourceNames: []string{name},
+		})
+	}
+
+	// the canary Service and its serving certificate are read next to the routes
+	if openshift.VerifyCanaryCertificate {
+		if target.CanaryService == "" {
+			errs = append(errs, errors.New("verifyCanaryCertificate reads the canary Service, whose name is only known from the Rollout"))
+		}
+		for _, route := range openshift.Routes {
+			if target.CanaryService == "" {
+				break
+			}
+			namespace, _ := splitRouteReference(route, rolloutNamespace)
+			permissions = append(permissions, permission{
+				Namespace:     namespace,
+				Resource:      "services",
+				Verbs:         []string{"get"},
+				ResourceNames: []string{target.CanaryService},
+			})
+			secret := target.ServingCertSecrets[namespace]
+			if secret == "" {
+				errs = append(errs, fmt.Errorf("verifyCanaryCertificate reads the serving certificate Secret of the canary Service %s/%s, "+
+					"whose name is only known from the manifest of the Service", namespace, target.CanaryService))
+				continue
+			}
+			permissions = append(permissions, permission{
+				Namespace:     namespace,
+				Resource:      "secrets",
+				Verbs:         []string{"get"},
+				ResourceNames: []string{secret},
+			})
+		}
+	}
+
+	// routes missing from the template are created, create cannot be restricted by name
+	if openshift.RouteTemplate != nil {
+		for _, route := range openshift.Routes {
+			namespace, _ := splitRouteReference(route, rolloutNamespace)
+			permissions = append(permissions, createRoutePermissions(namespace)...)
+		}
+	}
+
+	// the preview route is created next to the route it clones, create cannot be restricted by name
+	if openshift.PreviewRoute != nil && len(openshift.Routes) > 0 {
+		rollout := &v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Namespace: rolloutNamespace}}
+		namespace, _, name := previewNames(rollout, openshift)
+		permissions = append(permissions,
+			permission{
+				Namespace:     namespace,
+				Group:         routev1.GroupName,
+				Resource:      "routes",
+				Verbs:         []string{"get", "update"},
+				ResourceNames: []string{name},
+			},
+			permission{
+				Namespace:     namespace,
+				Group:         routev1.GroupName,
+				Resource:      "routes",
+				Verbs:         []string{"delete"},
+				ResourceNames: []string{name},
+			})
+		permissions = append(permissions, createRoutePermissions(namespace)...)
+	}
+
+	// routes in remote clusters are accessed with the identity of their kubeconfig,
+	// only the Secrets holding those kubeconfigs are read in the local cluster
+	for _, cluster := range openshift.ClusterRoutes {
+		namespace := cluster.KubeconfigSecret.Namespace
+		if namespace == "" {
+			namespace = rolloutNamespace
+		}
+		permissions = append(permissions, permission{
+			Namespace:     namespace,
+			Resource:      "secrets",
+			Verbs:         []string{"get"},
+			ResourceNames: []string{cluster.KubeconfigSecret.Name},
+		})
+	}
+	return permissions, errors.Join(errs...)
+}
+
+// createRoutePermissions returns the permissions to create routes in the namespace, with their host set.
+func createRoutePermissions(namespace string) []permission {
+	return []permission{
+		{Namespace: namespace, Group: routev1.GroupName, Resource: "routes", Verbs: []string{"create"}},
+		{Namespace: namespace, Group: routev1.GroupName, Resource: "routes/custom-host", Verbs: []string{"create"}},
+	}
+}
+
+// MergePolicyRules returns rules with more added, dropping the duplicates and merging the resource names of the
+// rules for the same group, resource and verbs, e.g. to gather the rules of several namespaces in a ClusterRole.
+func MergePolicyRules(rules []rbacv1.PolicyRule, more ...rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	for _, rule := range more {
+		// the resource names of the merged rules are appended to, they must not be shared with more
+		rules = addRule(rules, *rule.DeepCopy())
+	}
+	return rules
+}
+
+// addRule appends rule to rules, merging its resource names into an existing rule
+// for the same group, resource and verbs.
+func addRule(rules []rbacv1.PolicyRule, rule rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	for i, existing := range rules {
+		if reflect.DeepEqual(existing, rule) {
+			return rules
+		}
+		if reflect.DeepEqual(existing.APIGroups, rule.APIGroups) &&
+			reflect.DeepEqual(existing.Resources, rule.Resources) &&
+			reflect.DeepEqual(existing.Verbs, rule.Verbs) &&
+			len(existing.ResourceNames) > 0 && len(rule.ResourceNames) > 0 {
+			for _, name := range rule.ResourceNames {
+				if !slices.Contains(existing.ResourceNames, name) {
+					rules[i].ResourceNames = append(rules[i].ResourceNames, name)
+				}
+			}
+			return rules
+		}
+	}
+	return append(rules, rule)
+}
+
+// checkPermissions runs a SelfSubjectAccessReview for every verb and resource name of the permissions
+// and returns the accesses that are not allowed, e.g. `update routes.route.openshift.io "a" in namespace apps`.
+func (r *RpcPlugin) checkPermissions(ctx context.Context, permissions []permission) ([]string, error) {
+	var missing []string
+	for _, p := range permissions {
+		resource, subresource, _ := strings.Cut(p.Resource, "/")
+		names := p.ResourceNames
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, verb := range p.Verbs {
+			for _, name := range names {
+				review := &authorizationv1.SelfSubjectAccessReview{
+					Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+						ResourceAttributes: &authorizationv1.ResourceAttributes{
+							Namespace:   p.Namespace,
+							Group:       p.Group,
+							Resource:    resource,
+							Subresource: subresource,
+							Verb:        verb,
+							Name:        name,
+						},
+					},
+				}
+				result, err := r.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+				if err != nil {
+					return nil, fmt.Errorf("failed to review the access to %s: %w", p.describe(verb, name), err)
+				}
+				if !result.Status.Allowed {
+					missing = append(missing, p.describe(verb, name))
+				}
+			}
+		}
+	}
+	return missing, nil
+}
+
+// verifyPermissions logs the cluster-wide permissions the plugin is missing and,
+// if FailOnMissingPermissions is set, returns a Forbidden error listing them.
+func (r *RpcPlugin) verifyPermissions(ctx context.Context) error {
+	missing, err := r.checkPermissions(ctx, r.requiredPermissions())
+	if err != nil {
+		if r.FailOnMissingPermissions {
+			return err
+		}
+		slog.Warn("unable to check the plugin permissions", slog.Any("err", err))
+		return nil
+	}
+
+	for _, m := range missing {
+		slog.Warn("the plugin is missing a cluster-wide permission", slog.String("permission", m))
+	}
+	if len(missing) > 0 && r.FailOnMissingPermissions {
+		return newError(ReasonForbidden, nil, "missing permissions: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// permissionChecks remembers, for each rollout, the permissions already checked, so that they are
+// only reviewed again when the configuration of the rollout changes.
+type permissionChecks struct {
+	mu sync.Mutex
+	// enabled is set by InitPlugin, the commands using the plugin do not check the permissions
+	enabled bool
+	checked map[string]string
+}
+
+func (c *permissionChecks) enable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = true
+}
+
+func (c *permissionChecks) isEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled
+}
+
+// needed tells whether the permissions of the rollout, summed up by fingerprint, still need to be checked.
+func (c *permissionChecks) needed(rollout, fingerprint string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.checked[rollout] != fingerprint
+}
+
+func (c *permissionChecks) record(rollout, fingerprint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checked == nil {
+		c.checked = map[string]string{}
+	}
+	c.checked[rollout] = fingerprint
+}
+
+// verifyRolloutPermissions checks, in the namespaces they are used in, the permissions the plugin needs
+// to manage the rollout, logging the missing ones or, if FailOnMissingPermissions is set, returning
+// a Forbidden error listing them.
+func (r *RpcPlugin) verifyRolloutPermissions(ctx context.Context, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting) error {
+	if !r.permissionChecks.isEnabled() {
+		return nil
+	}
+	permissions, incomplete := rolloutPermissions(openshift, r.policyTarget(ctx, rollout, openshift))
+	fingerprint := fmt.Sprint(permissions)
+	if !r.permissionChecks.needed(rolloutKey(rollout), fingerprint) {
+		return nil
+	}
+	if incomplete != nil {
+		slog.Warn("unable to check some of the permissions of the rollout", slog.String("rollout", rolloutKey(rollout)), slog.Any("err", incomplete))
+	}
+
+	missing, err := r.checkPermissions(ctx, permissions)
+	if err != nil {
+		if r.FailOnMissingPermissions {
+			return err
+		}
+		slog.Warn("unable to check the permissions of the rollout", slog.String("rollout", rolloutKey(rollout)), slog.Any("err", err))
+		return nil
+	}
+	for _, m := range missing {
+		slog.Warn("the plugin is missing a permission the rollout needs", slog.String("rollout", rolloutKey(rollout)), slog.String("permission", m))
+	}
+	if len(missing) > 0 && r.FailOnMissingPermissions {
+		return newError(ReasonForbidden, nil, "rollout %s: missing permissions: %s", rolloutKey(rollout), strings.Join(missing, ", "))
+	}
+	r.permissionChecks.record(rolloutKey(rollout), fingerprint)
+	return nil
+}
+
+// verifyServedRollouts checks, when the plugin starts, the permissions of the rollouts already using it in the
+// namespaces it serves, as SetWeight would, so that missing ones show before their next canary step. With
+// FailOnMissingPermissions, it returns a Forbidden error listing the missing permissions of every rollout.
+func (r *RpcPlugin) verifyServedRollouts(ctx context.Context) error {
+	rollouts, err := r.servedRollouts(ctx)
+	if err != nil {
+		if r.FailOnMissingPermissions {
+			return err
+		}
+		slog.Warn("unable to list the rollouts to check their permissions", slog.Any("err", err))
+		return nil
+	}
+
+	var missing []string
+	for _, rollout := range rollouts {
+		openshift, err := r.rolloutRouting(rollout)
+		if err != nil {
+			// an invalid configuration fails the calls for the rollout with a clearer error
+			continue
+		}
+		if err := r.verifyRolloutPermissions(ctx, rollout, openshift); err != nil {
+			if ReasonOf(err) != ReasonForbidden {
+				return err
+			}
+			missing = append(missing, classifyError(err).Message)
+		}
+	}
+	if len(missing) > 0 {
+		return newError(ReasonForbidden, nil, "%s", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// servedRollouts lists the rollouts of the namespaces the plugin serves, every namespace when allowedNamespaces
+// is unset, that have a block under one of the names of the plugin. Unlike in the calls of the controller, the
+// only block of a rollout is not taken for the plugin's, since the rollouts listed may use other plugins.
+func (r *RpcPlugin) servedRollouts(ctx context.Context) ([]*v1alpha1.Rollout, error) {
+	global := r.globalConfig()
+	namespaces := global.AllowedNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	var rollouts []*v1alpha1.Rollout
+	for _, namespace := range namespaces {
+		list, err := r.rolloutClient.ArgoprojV1alpha1().Rollouts(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			canary := list.Items[i].Spec.Strategy.Canary
+			if canary == nil || canary.TrafficRouting == nil {
+				continue
+			}
+			if slices.ContainsFunc(pluginNames(global.PluginNames), func(name string) bool {
+				_, ok := canary.TrafficRouting.Plugins[name]
+				return ok
+			}) {
+				rollouts = append(rollouts, &list.Items[i])
+			}
+		}
+	}
+	return rollouts, nil
+}
+
+// policyTarget returns the rollout the permissions are derived for, reading the canary Services
+// for the names of their serving certificate Secrets when the rollout verifies them.
+func (r *RpcPlugin) policyTarget(ctx context.Context, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting) PolicyTarget {
+	target := PolicyTarget{Namespace: rollout.Namespace, CanaryService: rollout.Spec.Strategy.Canary.CanaryService}
+	if !openshift.VerifyCanaryCertificate || target.CanaryService == "" {
+		return target
+	}
+	target.ServingCertSecrets = map[string]string{}
+	for _, route := range openshift.Routes {
+		namespace, _ := splitRouteReference(route, rollout.Namespace)
+		service, err := r.kubeClient.CoreV1().Services(namespace).Get(ctx, target.CanaryService, metav1.GetOptions{})
+		if err == nil {
+			target.ServingCertSecrets[namespace] = ServingCertSecretName(service)
+		}
+	}
+	return target
+}
@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/testing"
+)
+
+var _ = Describe("Test RBAC permission checks", func() {
+	var (
+		ctx         context.Context
+		kubeClient  *k8sfake.Clientset
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		reviews     []authorizationv1.ResourceAttributes
+	)
+
+	// authorize makes the fake API server allow what the rules grant, per namespace,
+	// the rules under the empty namespace being cluster-wide
+	authorize := func(rules map[string][]rbacv1.PolicyRule) {
+		kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action testing.Action) (bool, runtime.Object, error) {
+			review := action.(testing.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			attributes := *review.Spec.ResourceAttributes
+			reviews = append(reviews, attributes)
+			resource := attributes.Resource
+			if attributes.Subresource != "" {
+				resource += "/" + attributes.Subresource
+			}
+			for _, rule := range append(rules[attributes.Namespace], rules[""]...) {
+				if slices.Contains(rule.APIGroups, attributes.Group) && slices.Contains(rule.Resources, resource) &&
+					slices.Contains(rule.Verbs, attributes.Verb) &&
+					(len(rule.ResourceNames) == 0 || slices.Contains(rule.ResourceNames, attributes.Name)) {
+					review.Status.Allowed = true
+				}
+			}
+			return true, review, nil
+		})
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kubeClient = k8sfake.NewSimpleClientset()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{kubeClient: kubeClient, routeClient: routeClient}
+		reviews = nil
+	})
+
+	It("should only check the cluster-wide access of the orphan sweeper", func() {
+		authorize(nil)
+		Expect(r.verifyPermissions(ctx)).To(Succeed())
+		Expect(reviews).To(BeEmpty())
+
+		r.SweepOrphanRoutes = true
+		missing, err := r.checkPermissions(ctx, r.requiredPermissions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(missing).To(ConsistOf("list routes.route.openshift.io cluster-wide", "delete routes.route.openshift.io cluster-wide",
+			"get rollouts.argoproj.io cluster-wide"))
+
+		reviews = nil
+		authorize(map[string][]rbacv1.PolicyRule{"": SweeperPolicyRules()})
+		missing, err = r.checkPermissions(ctx, r.requiredPermissions())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(missing).To(BeEmpty())
+		Expect(reviews).To(HaveLen(3))
+	})
+
+	It("should only fail when FailOnMissingPermissions is set", func() {
+		r.SweepOrphanRoutes = true
+		authorize(nil)
+		Expect(r.verifyPermissions(ctx)).To(Succeed())
+
+		r.FailOnMissingPermissions = true
+		err := r.verifyPermissions(ctx)
+		Expect(ReasonOf(err)).To(Equal(ReasonForbidden))
+		Expect(err.Error()).To(ContainSubstring("list routes.route.openshift.io cluster-wide"))
+	})
+
+	It("should tolerate a failing review unless FailOnMissingPermissions is set", func() {
+		r.SweepOrphanRoutes = true
+		kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action testing.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("unavailable")
+		})
+		Expect(r.verifyPermissions(ctx)).To(Succeed())
+
+		r.FailOnMissingPermissions = true
+		Expect(r.verifyPermissions(ctx)).ToNot(Succeed())
+	})
+
+	It("should check the routes of a rollout in their namespace once the plugin is initialized", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		authorize(map[string][]rbacv1.PolicyRule{mocks.Namespace: {{
+			APIGroups: []string{"route.openshift.io"}, Resources: []string{"routes"}, Verbs: []string{"get"}, ResourceNames: []string{mocks.RouteName},
+		}}})
+		r.FailOnMissingPermissions = true
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(reviews).To(BeEmpty())
+
+		r.permissionChecks.enable()
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.ErrorString).To(HavePrefix(`OpenshiftForbidden: rollout default/rollout: missing permissions: ` +
+			`update routes.route.openshift.io "argo-rollouts" in namespace default`))
+		Expect(reviews).To(ConsistOf(
+			authorizationv1.ResourceAttributes{Namespace: mocks.Namespace, Group: "route.openshift.io", Resource: "routes", Verb: "get", Name: mocks.RouteName},
+			authorizationv1.ResourceAttributes{Namespace: mocks.Namespace, Group: "route.openshift.io", Resource: "routes", Verb: "update", Name: mocks.RouteName},
+		))
+
+		r.FailOnMissingPermissions = false
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		reviews = nil
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(reviews).To(BeEmpty())
+	})
+
+	It("should check the rollouts already using the plugin when it starts", func() {
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		other := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, "other-route")
+		other.Name = "other-plugin"
+		other.Spec.Strategy.Canary.TrafficRouting.Plugins = map[string]json.RawMessage{"example.com/other": []byte(`{}`)}
+		elsewhere := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, "elsewhere-route")
+		elsewhere.Namespace = "apps"
+		r.rolloutClient = rolloutfake.NewSimpleClientset(rollout, other, elsewhere)
+		authorize(nil)
+		r.permissionChecks.enable()
+
+		Expect(r.verifyServedRollouts(ctx)).To(Succeed())
+		Expect(reviews).To(HaveLen(4))
+
+		r.FailOnMissingPermissions = true
+		r.permissionChecks = permissionChecks{enabled: true}
+		r.SetGlobalConfig(&GlobalConfig{AllowedNamespaces: []string{mocks.Namespace}})
+		err := r.verifyServedRollouts(ctx)
+		Expect(ReasonOf(err)).To(Equal(ReasonForbidden))
+		Expect(err.Error()).To(HavePrefix(`OpenshiftForbidden: rollout default/rollout: missing permissions: ` +
+			`get routes.route.openshift.io "argo-rollouts" in namespace default, update routes.route.openshift.io "argo-rollouts" in namespace default (hint:`))
+
+		// the rollouts checked at startup are not checked again by SetWeight
+		authorize(map[string][]rbacv1.PolicyRule{mocks.Namespace: {{
+			APIGroups: []string{"route.openshift.io"}, Resources: []string{"routes"}, Verbs: []string{"get", "update"},
+		}}})
+		Expect(r.verifyServedRollouts(ctx)).To(Succeed())
+		reviews = nil
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(reviews).To(BeEmpty())
+	})
+
+	It("should allow everything the generated rules grant", func() {
+		_, err := kubeClient.CoreV1().Services("edge").Create(ctx, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        mocks.CanaryServiceName,
+				Namespace:   "edge",
+				Annotations: map[string]string{"service.beta.openshift.io/serving-cert-secret-name": "canary-tls"},
+			},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.Namespace = "apps"
+		openshift := &OpenshiftTrafficRouting{
+			Routes:                  []string{"a", "edge/b"},
+			VerifyCanaryCertificate: true,
+			RouteTemplate:           &RouteTemplate{},
+			PreviewRoute:            &PreviewRoute{},
+			ClusterRoutes:           []ClusterRoutes{{KubeconfigSecret: SecretKeyReference{Name: "east", Namespace: "clusters"}, Routes: []string{"c"}}},
+		}
+
+		target := r.policyTarget(ctx, rollout, openshift)
+		Expect(target.ServingCertSecrets).To(HaveKeyWithValue("edge", "canary-tls"))
+		target.ServingCertSecrets["apps"] = "canary-tls"
+		rules := map[string][]rbacv1.PolicyRule{}
+		Expect(AddPolicyRules(rules, openshift, target)).To(Succeed())
+		rules[""] = SweeperPolicyRules()
+		authorize(rules)
+
+		permissions, err := rolloutPermissions(openshift, target)
+		Expect(err).ToNot(HaveOccurred())
+		r.SweepOrphanRoutes = true
+		missing, err := r.checkPermissions(ctx, append(permissions, r.requiredPermissions()...))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(missing).To(BeEmpty())
+		Expect(reviews).To(ContainElements(
+			HaveField("Subresource", "custom-host"),
+			authorizationv1.ResourceAttributes{Namespace: "edge", Resource: "secrets", Verb: "get", Name: "canary-tls"},
+			authorizationv1.ResourceAttributes{Namespace: "clusters", Resource: "secrets", Verb: "get", Name: "east"},
+			authorizationv1.ResourceAttributes{Namespace: "apps", Group: "route.openshift.io", Resource: "routes", Verb: "delete", Name: "a-preview"},
+		))
+
+		rules["edge"] = rules["edge"][1:]
+		missing, err = r.checkPermissions(ctx, permissions)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(missing).ToNot(BeEmpty())
+	})
+})
@@ -2,20 +2,24 @@ package plugin
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"strings"
+	"sync"
+	"sync/atomic"
 
 	"log/slog"
 
 	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/utils"
 	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
 	rolloutsPlugin "github.com/argoproj/argo-rollouts/rollout/trafficrouting/plugin/rpc"
 	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
 	routev1 "github.com/openshift/api/route/v1"
 	openshiftclientset "github.com/openshift/client-go/route/clientset/versioned"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 // Type holds this controller type
@@ -24,24 +28,93 @@ const ControllerType = "Openshift"
 var _ rolloutsPlugin.TrafficRouterPlugin = (*RpcPlugin)(nil)
 
 type RpcPlugin struct {
-	routeClient openshiftclientset.Interface
+	// KubeConfigOptions customizes the client configuration used to reach the cluster
+	KubeConfigOptions utils.KubeConfigOptions
+	// FailOnMissingPermissions makes InitPlugin fail when the plugin lacks any of the cluster-wide permissions
+	// it needs or of those the rollouts already using it need, and SetWeight fail when it lacks any of the
+	// permissions the routes of the rollout need
+	FailOnMissingPermissions bool
+	// SweepOrphanRoutes makes InitPlugin delete the routes the plugin created for rollouts that no longer exist
+	SweepOrphanRoutes bool
+
+	// clientsMu guards the clients against the health check, which runs while InitPlugin sets them
+	clientsMu     sync.RWMutex
+	routeClient   openshiftclientset.Interface
+	kubeClient    kubernetes.Interface
+	rolloutClient rolloutclientset.Interface
+
+	// clusters caches the route clients of remote clusters
+	clusters clusterClients
+	// canaryHashes holds the canary pod template hashes given to UpdateHash
+	canaryHashes canaryHashes
+	// newClusterClient builds the route client of a remote cluster, openshiftclientset.NewForConfig when nil
+	newClusterClient func(*rest.Config) (openshiftclientset.Interface, error)
+	// global holds the plugin configuration file, replaced when it is reloaded
+	global atomic.Pointer[GlobalConfig]
+	// state records the calls of the rollouts controller for the debug endpoint
+	state stateTracker
+	// tlsProblems remembers the TLS problems already logged for each route
+	tlsProblems tlsProblems
+	// permissionChecks remembers the permissions already checked for each rollout
+	permissionChecks permissionChecks
 }
 
-// OpenshiftTrafficRouting defines the configuration required to use Openshift routes for traffic
-type OpenshiftTrafficRouting struct {
-	// Routes is an array of strings which refer to the names of the Routes used to route traffic to the service
-	Routes []string `json:"routes" protobuf:"bytes,1,name=routes"`
+// NewForConfig returns a plugin reaching the cluster with cfg, for commands that do not go through InitPlugin.
+func NewForConfig(cfg *rest.Config) (*RpcPlugin, error) {
+	r := &RpcPlugin{}
+	if err := r.setClients(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
-func (r *RpcPlugin) InitPlugin() pluginTypes.RpcError {
-	cfg, err := utils.NewKubeConfig()
+// NewForClients returns a plugin using the given clients.
+func NewForClients(routeClient openshiftclientset.Interface, kubeClient kubernetes.Interface, rolloutClient rolloutclientset.Interface) *RpcPlugin {
+	return &RpcPlugin{routeClient: routeClient, kubeClient: kubeClient, rolloutClient: rolloutClient}
+}
+
+func (r *RpcPlugin) setClients(cfg *rest.Config) error {
+	routeClient, err := openshiftclientset.NewForConfig(cfg)
 	if err != nil {
-		return pluginTypes.RpcError{ErrorString: err.Error()}
+		return err
 	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	rolloutClient, err := rolloutclientset.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	r.clientsMu.Lock()
+	defer r.clientsMu.Unlock()
+	r.routeClient, r.kubeClient, r.rolloutClient = routeClient, kubeClient, rolloutClient
+	return nil
+}
 
-	r.routeClient, err = openshiftclientset.NewForConfig(cfg)
+func (r *RpcPlugin) InitPlugin() pluginTypes.RpcError {
+	cfg, err := utils.NewKubeConfig(r.KubeConfigOptions)
 	if err != nil {
-		return pluginTypes.RpcError{ErrorString: err.Error()}
+		return toRpcError(err)
+	}
+
+	if err := r.setClients(cfg); err != nil {
+		return toRpcError(err)
+	}
+
+	if err := r.verifyPermissions(context.Background()); err != nil {
+		return toRpcError(err)
+	}
+	r.permissionChecks.enable()
+	if err := r.verifyServedRollouts(context.Background()); err != nil {
+		return toRpcError(err)
+	}
+
+	if r.SweepOrphanRoutes {
+		if err := r.sweepOrphans(context.Background()); err != nil {
+			slog.Warn("unable to delete the routes left over by deleted rollouts", slog.Any("err", err))
+		}
 	}
 
 	return pluginTypes.RpcError{}
@@ -49,37 +122,57 @@ func (r *RpcPlugin) InitPlugin() pluginTypes.RpcError {
 
 // UpdateHash informs a traffic routing reconciler about new canary/stable pod hashes
 func (r *RpcPlugin) UpdateHash(ro *v1alpha1.Rollout, canaryHash, stableHash string, additionalDestinations []v1alpha1.WeightDestination) pluginTypes.RpcError {
+	r.canaryHashes.set(ro, canaryHash)
 	return pluginTypes.RpcError{}
 }
 
 // SetWeight modifies the OpenShift Route resource to reach the desired weight.
-func (r *RpcPlugin) SetWeight(rollout *v1alpha1.Rollout, desiredWeight int32, additionalDestinations []v1alpha1.WeightDestination) pluginTypes.RpcError {
+func (r *RpcPlugin) SetWeight(rollout *v1alpha1.Rollout, desiredWeight int32, additionalDestinations []v1alpha1.WeightDestination) (rpcErr pluginTypes.RpcError) {
+	defer func() {
+		observeCall("SetWeight", rpcErr)
+		r.state.setWeight(rollout, r.canaryHashes.get(rollout), desiredWeight, rpcErr)
+	}()
+
+	openshift, err := r.rolloutRouting(rollout)
+	if err != nil {
+		return toRpcError(err)
+	}
+
 	if err := validateRolloutParameters(rollout); err != nil {
-		return pluginTypes.RpcError{ErrorString: err.Error()}
+		return toRpcError(err)
+	}
+	if desiredWeight, err = openshift.limitWeight(rollout, desiredWeight); err != nil {
+		return toRpcError(err)
 	}
 
-	openshift, err := getOpenshiftRouting(rollout)
-	if err != nil {
-		return pluginTypes.RpcError{ErrorString: err.Error()}
+	ctx, cancel := r.requestContext()
+	defer cancel()
+
+	if err := r.verifyRolloutPermissions(ctx, rollout, openshift); err != nil {
+		return toRpcError(err)
 	}
 
-	ctx := context.Background()
+	targets, err := r.routeTargets(ctx, rollout, openshift)
+	if err != nil {
+		return toRpcError(err)
+	}
+	r.state.setRoutes(rollout, targets)
 
-	for _, route := range openshift.Routes {
-		slog.Info("updating route", slog.String("name", route), slog.Any("weight", desiredWeight))
-		namespace := rollout.Namespace
-		routeName := route
-		if strings.Contains(route, "/") {
-			namespace = strings.Split(route, "/")[0]
-			routeName = strings.Split(route, "/")[1]
-		}
-		if err := r.updateRoute(ctx, routeName, rollout, desiredWeight, namespace); err != nil {
-			slog.Error("failed to update route", slog.String("name", route), slog.Any("err", err))
-			return pluginTypes.RpcError{ErrorString: err.Error()}
+	var errs []error
+	for _, target := range targets {
+		slog.Info("updating route", slog.String("route", target.String()), slog.Any("weight", desiredWeight))
+		if err := r.updateRoute(ctx, target, rollout, openshift, desiredWeight, r.canaryHashes.get(rollout)); err != nil {
+			slog.Error("failed to update route", slog.String("route", target.String()), slog.Any("err", err))
+			errs = append(errs, withCluster(err, target.Cluster))
+			continue
 		}
-		slog.Info("successfully updated route", slog.String("name", route), slog.Any("weight", desiredWeight))
+		slog.Info("successfully updated route", slog.String("route", target.String()), slog.Any("weight", desiredWeight))
 	}
-	return pluginTypes.RpcError{}
+	if err := r.syncPreviewRoute(ctx, rollout, openshift, r.canaryHashes.get(rollout)); err != nil {
+		slog.Error("failed to sync preview route", slog.String("rollout", rolloutKey(rollout)), slog.Any("err", err))
+		errs = append(errs, err)
+	}
+	return toRpcError(joinErrors(errs))
 }
 
 func (r *RpcPlugin) SetHeaderRoute(ro *v1alpha1.Rollout, headerRouting *v1alpha1.SetHeaderRoute) pluginTypes.RpcError {
@@ -91,71 +184,327 @@ func (r *RpcPlugin) SetMirrorRoute(ro *v1alpha1.Rollout, setMirrorRoute *v1alpha
 }
 
 // Verifies weight of routes given by rollout
-func (r *RpcPlugin) VerifyWeight(rollout *v1alpha1.Rollout, desiredWeight int32, additionalDestinations []v1alpha1.WeightDestination) (pluginTypes.RpcVerified, pluginTypes.RpcError) {
-	return pluginTypes.NotImplemented, pluginTypes.RpcError{}
+func (r *RpcPlugin) VerifyWeight(rollout *v1alpha1.Rollout, desiredWeight int32, additionalDestinations []v1alpha1.WeightDestination) (result pluginTypes.RpcVerified, rpcErr pluginTypes.RpcError) {
+	defer func() {
+		observeCall("VerifyWeight", rpcErr)
+		r.state.verifyWeight(rollout, desiredWeight, result, rpcErr)
+	}()
+
+	openshift, err := r.rolloutRouting(rollout)
+	if err != nil {
+		return pluginTypes.NotVerified, toRpcError(err)
+	}
+	if desiredWeight, err = openshift.limitWeight(rollout, desiredWeight); err != nil {
+		return pluginTypes.NotVerified, toRpcError(err)
+	}
+
+	ctx, cancel := r.requestContext()
+	defer cancel()
+
+	targets, err := r.routeTargets(ctx, rollout, openshift)
+	if err != nil {
+		return pluginTypes.NotVerified, toRpcError(err)
+	}
+
+	verified := true
+	var errs []error
+	for _, target := range targets {
+		route, err := r.getRoute(ctx, target)
+		if err != nil {
+			errs = append(errs, withCluster(err, target.Cluster))
+			continue
+		}
+		// going back to the stable service is never held back by the routers
+		if desiredWeight > 0 {
+			if err := rejectionError(route, openshift.RouterShards); err != nil {
+				errs = append(errs, withCluster(err, target.Cluster))
+				continue
+			}
+		}
+		if desiredStable, desiredCanary := openshift.scaledWeights(desiredWeight); !backendsMatch(route, rollout, desiredStable, desiredCanary) {
+			stableWeight, canaryWeight := routeWeights(route)
+			slog.Info("route weights are not applied yet", slog.String("route", target.String()),
+				slog.Any("stableWeight", stableWeight), slog.Any("canaryWeight", canaryWeight), slog.Any("desiredWeight", desiredWeight),
+				slog.Any("drift", backendDrift(route, rollout)))
+			verified = false
+			continue
+		}
+		if desiredWeight == 0 {
+			continue
+		}
+
+		for _, admission := range admissionStatus(route, openshift.RouterShards) {
+			slog.Info("router shard admission", slog.String("route", target.String()), slog.String("router", admission.Router),
+				slog.Bool("reported", admission.Reported), slog.String("admitted", string(admission.Status)),
+				slog.String("reason", admission.Reason), slog.String("message", admission.Message))
+			if !admission.Admitted() {
+				verified = false
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return pluginTypes.NotVerified, toRpcError(joinErrors(errs))
+	}
+	if !verified {
+		return pluginTypes.NotVerified, pluginTypes.RpcError{}
+	}
+	if openshift.Mode == ModeAffinity && desiredWeight > 0 && desiredWeight < 100 {
+		slog.Info("the weights are applied, but in the affinity mode only new sessions follow them: "+
+			"the effective canary ratio lags the configured one until sessions started before this step expire",
+			slog.String("rollout", rolloutKey(rollout)), slog.Any("desiredWeight", desiredWeight),
+			slog.String("cookie", openshift.Affinity.cookieName(r.canaryHashes.get(rollout))))
+	}
+	return pluginTypes.Verified, pluginTypes.RpcError{}
 }
 
-func (r *RpcPlugin) RemoveManagedRoutes(ro *v1alpha1.Rollout) pluginTypes.RpcError {
-	return pluginTypes.RpcError{}
+// RemoveManagedRoutes restores the annotations the plugin changed on the routes during the canary
+// once the rollout no longer sends it traffic, and deletes the preview route once the canary is no longer in progress
+func (r *RpcPlugin) RemoveManagedRoutes(rollout *v1alpha1.Rollout) (rpcErr pluginTypes.RpcError) {
+	defer func() {
+		observeCall("RemoveManagedRoutes", rpcErr)
+		r.state.removeManagedRoutes(rollout, rpcErr)
+	}()
+
+	openshift, err := r.rolloutRouting(rollout)
+	if err != nil {
+		return toRpcError(err)
+	}
+
+	ctx, cancel := r.requestContext()
+	defer cancel()
+
+	targets, err := r.routeTargets(ctx, rollout, openshift)
+	if err != nil {
+		return toRpcError(err)
+	}
+
+	var errs []error
+	// while the rollout still sends traffic to the canary, SetWeight applies the annotations again right after,
+	// so they are only restored once its weight is back to 0, which SetWeight also does on its own
+	if currentWeight(rollout) == 0 {
+		for _, target := range targets {
+			if err := r.restoreRoute(ctx, target, rollout); err != nil {
+				slog.Error("failed to restore route", slog.String("route", target.String()), slog.Any("err", err))
+				errs = append(errs, withCluster(err, target.Cluster))
+			}
+		}
+	}
+	// the controller calls RemoveManagedRoutes at every reconcile of a full promotion, during which
+	// SetWeight keeps the preview route as syncPreviewRoute does, rather than deleting and creating it in turn
+	if !canaryInProgress(rollout, r.canaryHashes.get(rollout)) {
+		if err := r.deletePreviewRoute(ctx, rollout, openshift); err != nil {
+			slog.Error("failed to delete preview route", slog.String("rollout", rolloutKey(rollout)), slog.Any("err", err))
+			errs = append(errs, err)
+		}
+	}
+	return toRpcError(joinErrors(errs))
 }
 
 func (r *RpcPlugin) Type() string {
 	return ControllerType
 }
 
-func getOpenshiftRouting(rollout *v1alpha1.Rollout) (*OpenshiftTrafficRouting, error) {
-	var openshift OpenshiftTrafficRouting
-	if err := json.Unmarshal(rollout.Spec.Strategy.Canary.TrafficRouting.Plugins["argoproj-labs/openshift"], &openshift); err != nil {
+// getRoute returns the Route of the target, with a RouteNotFound error if it does not exist.
+func (r *RpcPlugin) getRoute(ctx context.Context, target routeTarget) (*routev1.Route, error) {
+	route, err := target.client.RouteV1().Routes(target.Namespace).Get(ctx, target.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			err = newError(ReasonRouteNotFound, nil, "route %q not found in namespace %q", target.Name, target.Namespace)
+			slog.Error(err.Error())
+		}
 		return nil, err
 	}
-	return &openshift, nil
+	return route, nil
 }
 
-// Update default backend weight,
-// remove alternateBackends if weight is 0,
-// otherwise update alternateBackends
-func (r *RpcPlugin) updateRoute(ctx context.Context, routeName string, rollout *v1alpha1.Rollout, desiredWeight int32, namespace string) error {
-	// get the route in the given namespace
-	openshiftRoute, err := r.routeClient.RouteV1().Routes(namespace).Get(ctx, routeName, metav1.GetOptions{})
+// updateRoute brings the route to the desired weight if it is not there yet
+func (r *RpcPlugin) updateRoute(ctx context.Context, target routeTarget, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting, desiredWeight int32, canaryHash string) error {
+	// get the route in the given namespace, creating it from the template of local routes
+	openshiftRoute, err := r.getRoute(ctx, target)
+	if ReasonOf(err) == ReasonRouteNotFound && openshift.RouteTemplate != nil && target.Cluster == "" {
+		if err := r.globalConfig().RouteNamespacePolicy.checkRoute(rollout, target.Namespace, target.Name, nil); err != nil {
+			return err
+		}
+		openshiftRoute, err = r.createRoute(ctx, target, rollout, openshift.RouteTemplate)
+	}
 	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			msg := fmt.Sprintf("Route %q not found", routeName)
-			slog.Error("OpenshiftRouteNotFound: " + msg)
+		return err
+	}
+	if err := r.checkRouteUpdate(ctx, target, openshiftRoute, rollout, openshift, desiredWeight); err != nil {
+		return err
+	}
+
+	desiredRoute, err := desiredRouteState(openshiftRoute, rollout, openshift, desiredWeight, canaryHash)
+	if err != nil {
+		return err
+	}
+	return writeRoute(ctx, target, openshiftRoute, desiredRoute)
+}
+
+// checkRouteUpdate refuses to change the weights of a route the rollout may not manage, or whose
+// changes would be reverted or break the canary. The rollout is nil when the route is restored on its own,
+// in which case the checks tied to the rollout are skipped.
+func (r *RpcPlugin) checkRouteUpdate(ctx context.Context, target routeTarget, route *routev1.Route, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting, desiredWeight int32) error {
+	if rollout != nil && target.Cluster == "" {
+		// the kubeconfig of a remote cluster decides which of its routes the plugin may manage
+		if err := r.globalConfig().RouteNamespacePolicy.checkRoute(rollout, target.Namespace, target.Name, route); err != nil {
+			return err
 		}
+	}
+	if err := checkIngressOwner(route); err != nil {
+		return err
+	}
+	if rollout == nil {
+		return nil
+	}
+	if err := checkDrift(route, rollout, openshift, desiredWeight); err != nil {
 		return err
 	}
+	if desiredWeight > 0 {
+		return r.checkTLS(ctx, target, route, rollout.Spec.Strategy.Canary.CanaryService, openshift)
+	}
+	return nil
+}
 
-	// update default backend weight if weight is different
-	altWeight := 100 - desiredWeight
-	if *openshiftRoute.Spec.To.Weight == altWeight {
+// writeRoute updates the route to the desired state if it differs from the current one.
+func writeRoute(ctx context.Context, target routeTarget, current, desired *routev1.Route) error {
+	if equality.Semantic.DeepEqual(current, desired) {
 		return nil
 	}
+	// the status of the response still reflects the previous spec: the routers only
+	// admit or reject the update later, which VerifyWeight checks
+	_, err := target.client.RouteV1().Routes(target.Namespace).Update(ctx, desired, metav1.UpdateOptions{})
+	return err
+}
+
+// desiredRouteState returns a copy of the route at the desired weight:
+// update default backend weight, recording the backends of the route before the canary,
+// remove alternateBackends and restore the canary annotations if weight is 0,
+// otherwise update alternateBackends and apply the canary annotations,
+// including the cookie name of the canary revision in the affinity mode
+func desiredRouteState(route *routev1.Route, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting, desiredWeight int32, canaryHash string) (*routev1.Route, error) {
+	route = route.DeepCopy()
+
+	altWeight, canaryWeight := openshift.scaledWeights(desiredWeight)
+	// routes restored without a rollout only get their weights back, there is no stable service to compare
+	changed := weightOf(route.Spec.To) != altWeight
+	if rollout != nil {
+		changed = !backendsMatch(route, rollout, altWeight, canaryWeight)
+	}
+	if changed {
+		if desiredWeight > 0 {
+			if err := recordSnapshot(route, rollout.Spec.Strategy.Canary.CanaryService); err != nil {
+				return nil, err
+			}
+		}
+		slog.Info("updating default backend weight", slog.Any("weight", altWeight))
+		if rollout != nil {
+			// backends changed outside the plugin are put back, checkDrift refused them with the Fail policy
+			route.Spec.To.Kind = "Service"
+			route.Spec.To.Name = rollout.Spec.Strategy.Canary.StableService
+		}
+		route.Spec.To.Weight = &altWeight
+		if desiredWeight == 0 {
+			slog.Info("deleting alternateBackends")
+			route.Spec.AlternateBackends = nil
+		} else {
+			slog.Info("updating alternate backend weight", slog.Any("weight", canaryWeight))
+			route.Spec.AlternateBackends = []routev1.RouteTargetReference{{
+				Kind:   "Service",
+				Name:   rollout.Spec.Strategy.Canary.CanaryService,
+				Weight: &canaryWeight,
+			}}
+		}
+	}
 
-	slog.Info("updating default backend weight to " + string(altWeight))
-	openshiftRoute.Spec.To.Weight = &altWeight
 	if desiredWeight == 0 {
-		slog.Info("deleting alternateBackends")
-		openshiftRoute.Spec.AlternateBackends = nil
-	} else {
-		slog.Info("updating alternate backend weight to " + string(desiredWeight))
-		openshiftRoute.Spec.AlternateBackends = []routev1.RouteTargetReference{{
-			Kind:   "Service",
-			Name:   rollout.Spec.Strategy.Canary.CanaryService,
-			Weight: &desiredWeight,
-		}}
-	}
-
-	_, err = r.routeClient.RouteV1().Routes(rollout.Namespace).Update(ctx, openshiftRoute, metav1.UpdateOptions{})
+		delete(route.Annotations, snapshotAnnotation)
+		return route, restoreAnnotations(route)
+	}
+	annotations := openshift.LoadBalancing.annotations()
+	if openshift.Mode == ModeAffinity && canaryHash != "" {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[cookieNameAnnotation] = openshift.Affinity.cookieName(canaryHash)
+	}
+	return route, applyCanaryAnnotations(route, annotations)
+}
+
+// restoreRoute puts back the annotations the plugin changed on the route and drops its snapshot
+func (r *RpcPlugin) restoreRoute(ctx context.Context, target routeTarget, rollout *v1alpha1.Rollout) error {
+	route, err := r.getRoute(ctx, target)
 	if err != nil {
 		return err
 	}
+	if target.Cluster == "" {
+		if err := r.globalConfig().RouteNamespacePolicy.checkRoute(rollout, target.Namespace, target.Name, route); err != nil {
+			return err
+		}
+	}
 
+	restored := route.DeepCopy()
+	delete(restored.Annotations, snapshotAnnotation)
+	if err := restoreAnnotations(restored); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(route, restored) {
+		return nil
+	}
+	if err := checkIngressOwner(route); err != nil {
+		return err
+	}
+
+	_, err = target.client.RouteV1().Routes(target.Namespace).Update(ctx, restored, metav1.UpdateOptions{})
 	return err
 }
 
+// routeWeights returns the weight of the default backend and the total weight of the alternate backends.
+func routeWeights(route *routev1.Route) (int32, int32) {
+	var canaryWeight int32
+	for _, backend := range route.Spec.AlternateBackends {
+		canaryWeight += weightOf(backend)
+	}
+	return weightOf(route.Spec.To), canaryWeight
+}
+
+// defaultWeightScale is the sum of the backend weights written to the routes when weightScale is not set
+const defaultWeightScale = 100
+
+// validateWeightScale checks that the scale is within the weights accepted by OpenShift
+// and fine enough for every percentage to be written exactly.
+func validateWeightScale(scale int32, path *field.Path) field.ErrorList {
+	if scale != 0 && (scale < 100 || scale > 256) {
+		return field.ErrorList{field.Invalid(path, scale, "must be between 100 and 256")}
+	}
+	return nil
+}
+
+// scaledWeights returns the weights of the default backend and of the canary sending desiredWeight percent
+// of the traffic to the canary.
+func (o *OpenshiftTrafficRouting) scaledWeights(desiredWeight int32) (int32, int32) {
+	scale := o.WeightScale
+	if scale == 0 {
+		scale = defaultWeightScale
+	}
+	canaryWeight := (desiredWeight*scale + 50) / 100
+	return scale - canaryWeight, canaryWeight
+}
+
+// weightOf returns the weight of a backend, which the router defaults to 100 when unset.
+func weightOf(backend routev1.RouteTargetReference) int32 {
+	if backend.Weight == nil {
+		return 100
+	}
+	return *backend.Weight
+}
+
 func validateRolloutParameters(rollout *v1alpha1.Rollout) error {
-	if rollout == nil || rollout.Spec.Strategy.Canary == nil || rollout.Spec.Strategy.Canary.StableService == "" || rollout.Spec.Strategy.Canary.CanaryService == "" {
-		return fmt.Errorf("illegal parameter(s)")
+	if rollout == nil || rollout.Spec.Strategy.Canary == nil {
+		return newError(ReasonInvalidConfig, nil, "rollout has no canary strategy")
+	}
+	if rollout.Spec.Strategy.Canary.StableService == "" || rollout.Spec.Strategy.Canary.CanaryService == "" {
+		return newError(ReasonServiceMissing, nil, "stable and canary services must both be set")
 	}
 	return nil
 }
@@ -142,7 +142,9 @@ var _ = Describe("Test TrafficRouter plugin for OpenShift route", func() {
 
 			rpcErr := routePlugin.SetWeight(rollout, desiredWeight, []v1alpha1.WeightDestination{})
 			Expect(rpcErr.HasError()).To(BeTrue())
-			Expect(rpcErr.Error()).To(Equal("unexpected end of JSON input"))
+			Expect(rpcErr.Error()).To(HavePrefix(string(ReasonInvalidConfig) + ": "))
+			Expect(rpcErr.Error()).To(ContainSubstring("spec.strategy.canary.trafficRouting.plugins: Required value: " +
+				"no configuration block for the plugin, expected one of argoproj-labs/openshift, found none"))
 		})
 
 		It("should return an error if the rollout canary strategy is not defined", func() {
@@ -152,7 +154,7 @@ var _ = Describe("Test TrafficRouter plugin for OpenShift route", func() {
 			rollout.Spec.Strategy.Canary = nil
 			rpcErr := routePlugin.SetWeight(rollout, desiredWeight, []v1alpha1.WeightDestination{})
 			Expect(rpcErr.HasError()).To(BeTrue())
-			Expect(rpcErr.Error()).To(Equal("illegal parameter(s)"))
+			Expect(rpcErr.Error()).To(HavePrefix(string(ReasonInvalidConfig) + ": "))
 		})
 
 		It("should return an error if the stable/canary service is not defined", func() {
@@ -162,7 +164,7 @@ var _ = Describe("Test TrafficRouter plugin for OpenShift route", func() {
 			rollout.Spec.Strategy.Canary.CanaryService = ""
 			rpcErr := routePlugin.SetWeight(rollout, desiredWeight, []v1alpha1.WeightDestination{})
 			Expect(rpcErr.HasError()).To(BeTrue())
-			Expect(rpcErr.Error()).To(Equal("illegal parameter(s)"))
+			Expect(rpcErr.Error()).To(HavePrefix(string(ReasonServiceMissing) + ": "))
 		})
 
 		It("should return an error if the specified route is not found", func() {
@@ -171,7 +173,7 @@ var _ = Describe("Test TrafficRouter plugin for OpenShift route", func() {
 
 			rpcErr := routePlugin.SetWeight(rollout, desiredWeight, []v1alpha1.WeightDestination{})
 			Expect(rpcErr.HasError()).To(BeTrue())
-			Expect(rpcErr.Error()).To(Equal(`routes.route.openshift.io "test-route" not found`))
+			Expect(rpcErr.Error()).To(HavePrefix(string(ReasonRouteNotFound) + `: route "test-route" not found in namespace "default"`))
 		})
 
 		It("should return an error if the route update fails", func() {
@@ -185,7 +187,7 @@ var _ = Describe("Test TrafficRouter plugin for OpenShift route", func() {
 
 			rpcErr := routePlugin.SetWeight(rollout, desiredWeight, []v1alpha1.WeightDestination{})
 			Expect(rpcErr.HasError()).To(BeTrue())
-			Expect(rpcErr.Error()).To(Equal(errMsg))
+			Expect(rpcErr.Error()).To(Equal(string(ReasonInternal) + ": " + errMsg))
 		})
 
 		It("should remove alternate backends if the desired weight is 0", func() {
@@ -259,14 +261,31 @@ var _ = Describe("Test TrafficRouter plugin for OpenShift route", func() {
 	})
 
 	When("VerifyWeight() is called", func() {
-		It("should return nil", func() {
+		It("should verify the weights set on the route", func() {
 			rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
 			desiredWeight := int32(30)
+
+			rpcErr := routePlugin.SetWeight(rollout, desiredWeight, []v1alpha1.WeightDestination{})
+			Expect(rpcErr.HasError()).To(BeFalse())
+
 			rpcVerified, rpcErr := routePlugin.VerifyWeight(rollout, desiredWeight, []v1alpha1.WeightDestination{})
 			Expect(rpcErr.HasError()).To(BeFalse())
 			Expect(rpcErr.Error()).To(BeEmpty())
+			Expect(rpcVerified).To(Equal(pluginTypes.Verified))
+		})
 
-			Expect(rpcVerified).To(Equal(pluginTypes.NotImplemented))
+		It("should not verify a route with different weights", func() {
+			rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+			rpcVerified, rpcErr := routePlugin.VerifyWeight(rollout, 30, []v1alpha1.WeightDestination{})
+			Expect(rpcErr.HasError()).To(BeFalse())
+			Expect(rpcVerified).To(Equal(pluginTypes.NotVerified))
+		})
+
+		It("should return an error if the route is not found", func() {
+			rollout := newRollout(mocks.StableServiceName, mocks.CanaryServiceName, "test-route")
+			rpcVerified, rpcErr := routePlugin.VerifyWeight(rollout, 30, []v1alpha1.WeightDestination{})
+			Expect(rpcErr.Error()).To(HavePrefix(string(ReasonRouteNotFound)))
+			Expect(rpcVerified).To(Equal(pluginTypes.NotVerified))
 		})
 	})
 
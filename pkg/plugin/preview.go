@@ -0,0 +1,268 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+
+	"log/slog"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// defaultPreviewHost is the hostname template of a preview Route when none is configured
+const defaultPreviewHost = "preview-{{ .Host }}"
+
+// PreviewRoute configures a Route that sends all of its traffic to the canary for the whole canary
+type PreviewRoute struct {
+	// Route is the managed route whose host settings are cloned, the first of Routes when empty
+	Route string `json:"route,omitempty"`
+	// Name of the preview Route, <route>-preview when empty
+	Name string `json:"name,omitempty"`
+	// Host is a text/template rendering the hostname of the preview Route, "preview-{{ .Host }}" when empty.
+	// It is given .Host, .Route, .Namespace, .Rollout and .Hash.
+	Host string `json:"host,omitempty"`
+	// Annotations are added to the preview Route
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// previewHostData is given to the hostname template of the preview Route
+type previewHostData struct {
+	// Host is the hostname of the cloned route
+	Host string
+	// Route is the name of the cloned route
+	Route     string
+	Namespace string
+	// Rollout is the name of the rollout
+	Rollout string
+	// Hash is the pod template hash of the canary
+	Hash string
+}
+
+func (p *PreviewRoute) hostTemplate() (*template.Template, error) {
+	host := p.Host
+	if host == "" {
+		host = defaultPreviewHost
+	}
+	return template.New("host").Option("missingkey=error").Parse(host)
+}
+
+func validatePreviewRoute(openshift *OpenshiftTrafficRouting, path *field.Path) field.ErrorList {
+	p := openshift.PreviewRoute
+	if p == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if p.Route != "" && !slices.Contains(openshift.Routes, p.Route) {
+		errs = append(errs, field.Invalid(path.Child("route"), p.Route, "must be one of the routes of the local cluster"))
+	} else if p.Route == "" && len(openshift.Routes) == 0 {
+		errs = append(errs, field.Required(path.Child("route"), "a route of the local cluster is needed to clone the host settings from"))
+	}
+	if p.Name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(p.Name) {
+			errs = append(errs, field.Invalid(path.Child("name"), p.Name, msg))
+		}
+		for _, route := range openshift.Routes {
+			if _, name := splitRouteReference(route, ""); name == p.Name {
+				errs = append(errs, field.Invalid(path.Child("name"), p.Name, "must not be the name of a managed route"))
+			}
+		}
+	}
+
+	tmpl, err := p.hostTemplate()
+	if err != nil {
+		errs = append(errs, field.Invalid(path.Child("host"), p.Host, err.Error()))
+	} else if err := tmpl.Execute(&bytes.Buffer{}, previewHostData{}); err != nil {
+		errs = append(errs, field.Invalid(path.Child("host"), p.Host, err.Error()))
+	}
+
+	for key := range p.Annotations {
+		if strings.HasPrefix(key, annotationPrefix) {
+			errs = append(errs, field.Forbidden(path.Child("annotations").Key(key), "the annotation is reserved for the plugin"))
+		}
+	}
+	return errs
+}
+
+// canaryInProgress tells whether the rollout is running a canary that has not been promoted or aborted.
+func canaryInProgress(rollout *v1alpha1.Rollout, canaryHash string) bool {
+	status := rollout.Status
+	if status.Abort || canaryHash == "" || status.StableRS == "" {
+		return false
+	}
+	return canaryHash != status.StableRS
+}
+
+// previewNames returns the namespace of the preview Route, the name of the route it clones and its own name.
+func previewNames(rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting) (string, string, string) {
+	source := openshift.PreviewRoute.Route
+	if source == "" {
+		source = openshift.Routes[0]
+	}
+	namespace, name := splitRouteReference(source, rollout.Namespace)
+	previewName := openshift.PreviewRoute.Name
+	if previewName == "" {
+		previewName = name + "-preview"
+	}
+	return namespace, name, previewName
+}
+
+// syncPreviewRoute creates or updates the preview Route while the canary is in progress and deletes it otherwise.
+func (r *RpcPlugin) syncPreviewRoute(ctx context.Context, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting, canaryHash string) error {
+	if openshift.PreviewRoute == nil {
+		return nil
+	}
+	if !canaryInProgress(rollout, canaryHash) {
+		return r.deletePreviewRoute(ctx, rollout, openshift)
+	}
+
+	namespace, sourceName, previewName := previewNames(rollout, openshift)
+	source, err := r.getRoute(ctx, routeTarget{Namespace: namespace, Name: sourceName, client: r.routeClient})
+	if err != nil {
+		return err
+	}
+	if err := r.globalConfig().RouteNamespacePolicy.checkRoute(rollout, namespace, sourceName, source); err != nil {
+		return err
+	}
+	desired, err := previewRouteFor(source, previewName, rollout, openshift.PreviewRoute, canaryHash)
+	if err != nil {
+		return err
+	}
+	if err := checkHost(r.globalConfig().AllowedHostDomains, rollout, "previewRoute", desired.Spec.Host); err != nil {
+		return err
+	}
+
+	routes := r.routeClient.RouteV1().Routes(namespace)
+	existing, err := routes.Get(ctx, previewName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		slog.Info("creating preview route", slog.String("route", namespace+"/"+previewName), slog.String("host", desired.Spec.Host))
+		_, err = routes.Create(ctx, desired, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if !ownedBy(existing, rollout) {
+		return &PluginError{
+			Reason:  ReasonConflict,
+			Message: "route " + namespace + "/" + previewName + " exists and is not the preview route of rollout " + rolloutKey(rollout),
+			Hint:    "delete the route or set previewRoute.name to another name",
+		}
+	}
+
+	updated := existing.DeepCopy()
+	updated.Spec = desired.Spec
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	maps.Copy(updated.Labels, desired.Labels)
+	if len(desired.Annotations) > 0 && updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	maps.Copy(updated.Annotations, desired.Annotations)
+	if equality.Semantic.DeepEqual(existing, updated) {
+		return nil
+	}
+	slog.Info("updating preview route", slog.String("route", namespace+"/"+previewName), slog.String("host", desired.Spec.Host))
+	_, err = routes.Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// deletePreviewRoute deletes the preview Route of the rollout if it exists.
+func (r *RpcPlugin) deletePreviewRoute(ctx context.Context, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting) error {
+	if openshift.PreviewRoute == nil {
+		return nil
+	}
+
+	namespace, _, previewName := previewNames(rollout, openshift)
+	routes := r.routeClient.RouteV1().Routes(namespace)
+	existing, err := routes.Get(ctx, previewName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := r.globalConfig().RouteNamespacePolicy.checkRoute(rollout, namespace, previewName, existing); err != nil {
+		return err
+	}
+	if !ownedBy(existing, rollout) {
+		slog.Warn("not deleting a route that is not the preview route of the rollout",
+			slog.String("route", namespace+"/"+previewName), slog.String("rollout", rolloutKey(rollout)))
+		return nil
+	}
+
+	slog.Info("deleting preview route", slog.String("route", namespace+"/"+previewName))
+	err = routes.Delete(ctx, previewName, metav1.DeleteOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// previewRouteFor returns the preview Route cloning the host settings of source and sending all traffic to the canary.
+func previewRouteFor(source *routev1.Route, name string, rollout *v1alpha1.Rollout, p *PreviewRoute, canaryHash string) (*routev1.Route, error) {
+	host := source.Spec.Host
+	if host == "" && len(source.Status.Ingress) > 0 {
+		// the host generated by the router
+		host = source.Status.Ingress[0].Host
+	}
+	if host == "" {
+		return nil, newError(ReasonInvalidConfig, nil, "route %s/%s has no host to derive the preview host from", source.Namespace, source.Name)
+	}
+
+	tmpl, err := p.hostTemplate()
+	if err != nil {
+		return nil, newError(ReasonInvalidConfig, err, "invalid previewRoute.host")
+	}
+	var rendered bytes.Buffer
+	err = tmpl.Execute(&rendered, previewHostData{
+		Host:      host,
+		Route:     source.Name,
+		Namespace: source.Namespace,
+		Rollout:   rollout.Name,
+		Hash:      canaryHash,
+	})
+	if err != nil {
+		return nil, newError(ReasonInvalidConfig, err, "invalid previewRoute.host")
+	}
+	previewHost := strings.TrimSpace(rendered.String())
+	if msgs := validation.IsDNS1123Subdomain(previewHost); len(msgs) > 0 {
+		return nil, newError(ReasonInvalidConfig, nil, "invalid preview host %q: %s", previewHost, strings.Join(msgs, ", "))
+	}
+
+	weight := int32(100)
+	preview := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: source.Namespace,
+			// the labels select the router shards serving the route
+			Labels:      withoutOwnerLabels(source.Labels),
+			Annotations: maps.Clone(p.Annotations),
+		},
+		Spec: routev1.RouteSpec{
+			Host:           previewHost,
+			Path:           source.Spec.Path,
+			Port:           source.Spec.Port.DeepCopy(),
+			TLS:            source.Spec.TLS.DeepCopy(),
+			WildcardPolicy: source.Spec.WildcardPolicy,
+			To: routev1.RouteTargetReference{
+				Kind:   "Service",
+				Name:   rollout.Spec.Strategy.Canary.CanaryService,
+				Weight: &weight,
+			},
+		},
+	}
+	setOwner(preview, rollout)
+	return preview, nil
+}
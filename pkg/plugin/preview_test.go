@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Test the preview route", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	previewName := mocks.RouteName + "-preview"
+
+	getPreview := func() (*routev1.Route, error) {
+		return routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, previewName, metav1.GetOptions{})
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		route.Labels = map[string]string{"type": "public"}
+		route.Spec.Host = "app.example.com"
+		route.Spec.Path = "/shop"
+		route.Spec.TLS = &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}
+		_, err = routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		rollout.UID = "rollout-uid"
+		rollout.Status.StableRS = "stable-hash"
+		rollout.Status.CurrentPodHash = "canary-hash"
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:       []string{mocks.RouteName},
+			PreviewRoute: &PreviewRoute{Annotations: map[string]string{"team": "qa"}},
+		})
+	})
+
+	It("should create a route sending all traffic to the canary during the canary", func() {
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+
+		preview, err := getPreview()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview.Spec.Host).To(Equal("preview-app.example.com"))
+		Expect(preview.Spec.Path).To(Equal("/shop"))
+		Expect(preview.Spec.TLS.Termination).To(Equal(routev1.TLSTerminationEdge))
+		Expect(preview.Spec.To.Name).To(Equal(mocks.CanaryServiceName))
+		Expect(*preview.Spec.To.Weight).To(Equal(int32(100)))
+		Expect(preview.Spec.AlternateBackends).To(BeEmpty())
+		Expect(preview.Labels).To(HaveKeyWithValue("type", "public"))
+		Expect(preview.Annotations).To(HaveKeyWithValue("team", "qa"))
+		Expect(preview.Labels).To(HaveKeyWithValue(managedByLabel, managedByValue))
+		Expect(preview.OwnerReferences).To(HaveLen(1))
+		Expect(preview.OwnerReferences[0].Name).To(Equal("rollout"))
+
+		// later steps keep it as is
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		_, err = getPreview()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should render the hostname template", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:       []string{mocks.RouteName},
+			PreviewRoute: &PreviewRoute{Name: "canary", Host: "{{ .Rollout }}-{{ .Hash }}.apps.example.com"},
+		})
+		r.canaryHashes.set(rollout, "abc123")
+		Expect(r.SetWeight(rollout, 10, nil).HasError()).To(BeFalse())
+
+		preview, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, "canary", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview.Spec.Host).To(Equal("rollout-abc123.apps.example.com"))
+	})
+
+	It("should not copy the owner labels of a route the plugin created", func() {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		setOwner(route, &v1alpha1.Rollout{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "apps", UID: "other-uid"}})
+		_, err = routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		rollout.UID = ""
+		Expect(r.SetWeight(rollout, 10, nil).HasError()).To(BeFalse())
+		preview, err := getPreview()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview.Labels).To(Equal(map[string]string{
+			"type":                "public",
+			managedByLabel:        managedByValue,
+			rolloutNamespaceLabel: mocks.Namespace,
+			rolloutNameLabel:      rollout.Name,
+		}))
+	})
+
+	It("should delete the route when the canary is aborted", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		rollout.Status.Abort = true
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		_, err := getPreview()
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should delete the route once the canary is promoted", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		rollout.Status.StableRS = rollout.Status.CurrentPodHash
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		_, err := getPreview()
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should delete the route in RemoveManagedRoutes once the canary is no longer in progress", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+		_, err := getPreview()
+		Expect(err).ToNot(HaveOccurred())
+
+		rollout.Status.Abort = true
+		Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+		_, err = getPreview()
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should keep the route while the controller alternates RemoveManagedRoutes and SetWeight", func() {
+		rollout.Status.PromoteFull = true
+		for range 3 {
+			Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+			Expect(r.SetWeight(rollout, 100, nil).HasError()).To(BeFalse())
+		}
+
+		var creates, deletes int
+		for _, action := range routeClient.Actions() {
+			switch action.GetVerb() {
+			case "create":
+				creates++
+			case "delete":
+				deletes++
+			}
+		}
+		Expect(creates).To(Equal(1))
+		Expect(deletes).To(BeZero())
+	})
+
+	It("should leave alone a route of the same name it does not own", func() {
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Create(ctx, &routev1.Route{
+			ObjectMeta: metav1.ObjectMeta{Name: previewName, Namespace: mocks.Namespace},
+			Spec:       routev1.RouteSpec{Host: "other.example.com"},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.Error()).To(HavePrefix(string(ReasonConflict) + ": route default/argo-rollouts-preview exists and is not the preview route of rollout default/rollout"))
+
+		Expect(r.RemoveManagedRoutes(rollout).HasError()).To(BeFalse())
+		preview, err := getPreview()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview.Spec.Host).To(Equal("other.example.com"))
+	})
+
+	DescribeTable("should validate the configuration",
+		func(preview PreviewRoute, expected string) {
+			setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, PreviewRoute: &preview})
+			_, err := getOpenshiftRouting(rollout)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expected))
+		},
+		Entry("unknown route", PreviewRoute{Route: "other"},
+			`previewRoute.route: Invalid value: "other": must be one of the routes of the local cluster`),
+		Entry("managed route name", PreviewRoute{Name: mocks.RouteName},
+			"must not be the name of a managed route"),
+		Entry("unknown template field", PreviewRoute{Host: "{{ .Cluster }}.example.com"},
+			"previewRoute.host: Invalid value"),
+		Entry("reserved annotation", PreviewRoute{Annotations: map[string]string{annotationPrefix + "rollout-name": "x"}},
+			"the annotation is reserved for the plugin"),
+	)
+})
@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// snapshotAnnotation holds, as JSON, the backends of the route before the plugin first sent traffic to the canary
+const snapshotAnnotation = annotationPrefix + "snapshot"
+
+// routeSnapshot is the state of the backends of a route before a canary
+type routeSnapshot struct {
+	To                routev1.RouteTargetReference   `json:"to"`
+	AlternateBackends []routev1.RouteTargetReference `json:"alternateBackends,omitempty"`
+}
+
+// RestoreMode selects the state restore brings the routes back to
+type RestoreMode string
+
+const (
+	// RestoreStable sends all the traffic to the stable service, as SetWeight 0 does
+	RestoreStable RestoreMode = "stable"
+	// RestoreSnapshot puts back the backends the route had before the canary
+	RestoreSnapshot RestoreMode = "snapshot"
+)
+
+// RestorePlan is the change restore makes to a route
+type RestorePlan struct {
+	// Route is the route as <namespace>/<name>, prefixed with <cluster>: for remote clusters
+	Route string
+	// Current is the route as it is
+	Current *routev1.Route
+	// Restored is the route once restored, nil when the route could not be planned
+	Restored *routev1.Route
+	// Err is set when the route cannot be restored
+	Err error
+
+	target routeTarget
+}
+
+// Changed tells whether restoring the route changes it
+func (p RestorePlan) Changed() bool {
+	return p.Restored != nil && !equality.Semantic.DeepEqual(p.Current, p.Restored)
+}
+
+// recordSnapshot records the backends of the route before the plugin first sends traffic to the canary.
+func recordSnapshot(route *routev1.Route, canaryService string) error {
+	if _, ok := route.Annotations[snapshotAnnotation]; ok {
+		return nil
+	}
+	for _, backend := range route.Spec.AlternateBackends {
+		if backend.Name == canaryService {
+			// the canary started before snapshots were recorded
+			return nil
+		}
+	}
+
+	encoded, err := json.Marshal(routeSnapshot{To: route.Spec.To, AlternateBackends: route.Spec.AlternateBackends})
+	if err != nil {
+		return err
+	}
+	if route.Annotations == nil {
+		route.Annotations = map[string]string{}
+	}
+	route.Annotations[snapshotAnnotation] = string(encoded)
+	return nil
+}
+
+// snapshotRouteState returns a copy of the route with the backends and annotations it had before the canary.
+func snapshotRouteState(route *routev1.Route) (*routev1.Route, error) {
+	encoded, ok := route.Annotations[snapshotAnnotation]
+	if !ok {
+		return nil, newError(ReasonInvalidConfig, nil, "route %s/%s has no recorded snapshot", route.Namespace, route.Name)
+	}
+	var snapshot routeSnapshot
+	if err := json.Unmarshal([]byte(encoded), &snapshot); err != nil {
+		return nil, newError(ReasonInvalidConfig, err, "invalid %s annotation on route %s/%s", snapshotAnnotation, route.Namespace, route.Name)
+	}
+
+	route = route.DeepCopy()
+	route.Spec.To = snapshot.To
+	route.Spec.AlternateBackends = snapshot.AlternateBackends
+	delete(route.Annotations, snapshotAnnotation)
+	return route, restoreAnnotations(route)
+}
+
+// PlanRestore computes how restore would change the routes managed for the rollout,
+// or only the given route, as <namespace>/<name>, when routeRef is set.
+// Without a rollout, routeRef is a route of the local cluster.
+func (r *RpcPlugin) PlanRestore(ctx context.Context, rollout *v1alpha1.Rollout, routeRef string, mode RestoreMode) ([]RestorePlan, error) {
+	var openshift *OpenshiftTrafficRouting
+	var targets []routeTarget
+	if rollout != nil {
+		var err error
+		if openshift, err = r.rolloutRouting(rollout); err != nil {
+			return nil, err
+		}
+		if targets, err = r.routeTargets(ctx, rollout, openshift); err != nil {
+			return nil, err
+		}
+	} else {
+		// the weight scale of the route comes from the defaults of the plugin configuration file
+		openshift = &OpenshiftTrafficRouting{}
+		r.globalConfig().Defaults.apply(openshift)
+	}
+
+	if routeRef != "" {
+		namespace, name := splitRouteReference(routeRef, "")
+		if rollout == nil {
+			targets = []routeTarget{{Namespace: namespace, Name: name, client: r.routeClient}}
+		} else {
+			var selected []routeTarget
+			for _, target := range targets {
+				if target.Namespace == namespace && target.Name == name {
+					selected = append(selected, target)
+				}
+			}
+			if len(selected) == 0 {
+				return nil, newError(ReasonInvalidConfig, nil, "route %s is not managed for rollout %s", routeRef, rolloutKey(rollout))
+			}
+			targets = selected
+		}
+	}
+
+	plans := make([]RestorePlan, 0, len(targets))
+	for _, target := range targets {
+		plan := RestorePlan{Route: target.String(), target: target}
+		plan.Current, plan.Err = r.getRoute(ctx, target)
+		if plan.Err == nil {
+			// weight 0 is never refused by the drift policy
+			plan.Err = r.checkRouteUpdate(ctx, target, plan.Current, rollout, openshift, 0)
+		}
+		if plan.Err == nil {
+			switch mode {
+			case RestoreSnapshot:
+				plan.Restored, plan.Err = snapshotRouteState(plan.Current)
+			case RestoreStable:
+				// at weight 0 the desired state only depends on the weight scale of the configuration
+				plan.Restored, plan.Err = desiredRouteState(plan.Current, rollout, openshift, 0, "")
+			default:
+				plan.Err = fmt.Errorf("unknown restore mode %q", mode)
+			}
+		}
+		if plan.Err != nil {
+			plan.Err = withCluster(plan.Err, target.Cluster)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// ApplyRestore updates the routes of the plans that change, as SetWeight does. The update fails
+// with a conflict if a route was modified since it was planned.
+func (r *RpcPlugin) ApplyRestore(ctx context.Context, plans []RestorePlan) error {
+	var errs []error
+	for _, plan := range plans {
+		if plan.Err != nil || !plan.Changed() {
+			continue
+		}
+		if err := writeRoute(ctx, plan.target, plan.Current, plan.Restored); err != nil {
+			errs = append(errs, withCluster(err, plan.target.Cluster))
+		}
+	}
+	return joinErrors(errs)
+}
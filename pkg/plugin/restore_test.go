@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Test restoring routes", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		// the route shared its traffic with a legacy service before the canary
+		routeClient = fake.NewSimpleClientset(&routev1.Route{
+			ObjectMeta: metav1.ObjectMeta{Name: "main-route", Namespace: mocks.Namespace},
+			Spec: routev1.RouteSpec{
+				To:                routev1.RouteTargetReference{Kind: "Service", Name: "stable", Weight: ptr.To[int32](90)},
+				AlternateBackends: []routev1.RouteTargetReference{{Kind: "Service", Name: "legacy", Weight: ptr.To[int32](10)}},
+			},
+		})
+		r = NewForClients(routeClient, k8sfake.NewSimpleClientset(), rolloutfake.NewSimpleClientset())
+
+		config, err := json.Marshal(OpenshiftTrafficRouting{Routes: []string{"main-route"}})
+		Expect(err).ToNot(HaveOccurred())
+		rollout = &v1alpha1.Rollout{
+			ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: mocks.Namespace},
+			Spec: v1alpha1.RolloutSpec{Strategy: v1alpha1.RolloutStrategy{Canary: &v1alpha1.CanaryStrategy{
+				StableService: "stable",
+				CanaryService: "canary",
+				TrafficRouting: &v1alpha1.RolloutTrafficRouting{
+					Plugins: map[string]json.RawMessage{PluginName: config},
+				},
+			}}},
+		}
+	})
+
+	getRoute := func() *routev1.Route {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, "main-route", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return route
+	}
+
+	It("should record the backends of the route before the canary once", func() {
+		Expect(r.SetWeight(rollout, 20, nil).HasError()).To(BeFalse())
+		snapshot := getRoute().Annotations[snapshotAnnotation]
+		Expect(snapshot).To(MatchJSON(`{"to":{"kind":"Service","name":"stable","weight":90},
+			"alternateBackends":[{"kind":"Service","name":"legacy","weight":10}]}`))
+
+		Expect(r.SetWeight(rollout, 50, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations[snapshotAnnotation]).To(Equal(snapshot))
+
+		Expect(r.SetWeight(rollout, 0, nil).HasError()).To(BeFalse())
+		Expect(getRoute().Annotations).ToNot(HaveKey(snapshotAnnotation))
+	})
+
+	It("should restore the route to the stable service", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		plans, err := r.PlanRestore(ctx, rollout, "", RestoreStable)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].Route).To(Equal(mocks.Namespace + "/main-route"))
+		Expect(plans[0].Err).ToNot(HaveOccurred())
+		Expect(plans[0].Changed()).To(BeTrue())
+
+		Expect(r.ApplyRestore(ctx, plans)).To(Succeed())
+		route := getRoute()
+		Expect(*route.Spec.To.Weight).To(Equal(int32(100)))
+		Expect(route.Spec.AlternateBackends).To(BeEmpty())
+		Expect(route.Annotations).ToNot(HaveKey(snapshotAnnotation))
+
+		plans, err = r.PlanRestore(ctx, rollout, "", RestoreStable)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans[0].Changed()).To(BeFalse())
+	})
+
+	It("should restore the route to its snapshot", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		plans, err := r.PlanRestore(ctx, nil, mocks.Namespace+"/main-route", RestoreSnapshot)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.ApplyRestore(ctx, plans)).To(Succeed())
+
+		route := getRoute()
+		Expect(*route.Spec.To.Weight).To(Equal(int32(90)))
+		Expect(route.Spec.AlternateBackends).To(Equal([]routev1.RouteTargetReference{{Kind: "Service", Name: "legacy", Weight: ptr.To[int32](10)}}))
+		Expect(route.Annotations).ToNot(HaveKey(snapshotAnnotation))
+	})
+
+	It("should report routes without a snapshot", func() {
+		plans, err := r.PlanRestore(ctx, rollout, "", RestoreSnapshot)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans[0].Err).To(MatchError(ContainSubstring("has no recorded snapshot")))
+		Expect(r.ApplyRestore(ctx, plans)).To(Succeed())
+	})
+
+	It("should only restore routes managed for the rollout", func() {
+		_, err := r.PlanRestore(ctx, rollout, mocks.Namespace+"/other", RestoreStable)
+		Expect(err).To(MatchError(ContainSubstring("is not managed for rollout")))
+	})
+
+	It("should restore a route on its own at the weight scale of the configuration file", func() {
+		config, err := ParseGlobalConfig([]byte("defaults:\n  weightScale: 256\n"))
+		Expect(err).ToNot(HaveOccurred())
+		r.SetGlobalConfig(config)
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		plans, err := r.PlanRestore(ctx, nil, mocks.Namespace+"/main-route", RestoreStable)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.ApplyRestore(ctx, plans)).To(Succeed())
+		Expect(*getRoute().Spec.To.Weight).To(Equal(int32(256)))
+	})
+
+	It("should refuse the routes SetWeight refuses", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		route := getRoute()
+		route.OwnerReferences = []metav1.OwnerReference{{APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: "frontend"}}
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		plans, err := r.PlanRestore(ctx, rollout, "", RestoreStable)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ReasonOf(plans[0].Err)).To(Equal(ReasonConflict))
+
+		r.SetGlobalConfig(&GlobalConfig{RouteNamespacePolicy: &RouteNamespacePolicy{}})
+		rollout.Namespace = "apps"
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.Namespace + "/main-route"}})
+		plans, err = r.PlanRestore(ctx, rollout, "", RestoreStable)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ReasonOf(plans[0].Err)).To(Equal(ReasonForbidden))
+	})
+
+})
@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+
+	"log/slog"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// RouteTemplate describes the Routes the plugin creates when a route of the configuration does not exist
+type RouteTemplate struct {
+	// Host is a text/template rendering the hostname of the Route, given .Route, .Namespace and .Rollout.
+	// It must render a different host for each route, and the router generates one when empty.
+	Host string `json:"host,omitempty"`
+	// Path the Route matches, every path when empty
+	Path string `json:"path,omitempty"`
+	// Port is the target port of the Services, by name or number
+	Port *intstr.IntOrString `json:"port,omitempty"`
+	// TLS configures the TLS termination of the Route
+	TLS *routev1.TLSConfig `json:"tls,omitempty"`
+	// Annotations are set on the Route
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// routeHostData is given to the hostname template of the routes created from the route template
+type routeHostData struct {
+	// Route is the name of the route
+	Route     string
+	Namespace string
+	// Rollout is the name of the rollout
+	Rollout string
+}
+
+func (t *RouteTemplate) hostTemplate() (*template.Template, error) {
+	return template.New("host").Option("missingkey=error").Parse(t.Host)
+}
+
+// host renders the hostname of the route, empty to let the router generate it.
+func (t *RouteTemplate) host(data routeHostData) (string, error) {
+	tmpl, err := t.hostTemplate()
+	if err != nil {
+		return "", err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(rendered.String()), nil
+}
+
+var tlsTerminations = []string{
+	string(routev1.TLSTerminationEdge),
+	string(routev1.TLSTerminationPassthrough),
+	string(routev1.TLSTerminationReencrypt),
+}
+
+var insecureEdgeTerminationPolicies = []string{
+	string(routev1.InsecureEdgeTerminationPolicyNone),
+	string(routev1.InsecureEdgeTerminationPolicyAllow),
+	string(routev1.InsecureEdgeTerminationPolicyRedirect),
+}
+
+func validateRouteTemplate(openshift *OpenshiftTrafficRouting, path *field.Path) field.ErrorList {
+	template := openshift.RouteTemplate
+	if template == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	if template.Host != "" {
+		errs = append(errs, validateRouteTemplateHost(template, openshift.Routes, path.Child("host"))...)
+	}
+	if template.Path != "" && !strings.HasPrefix(template.Path, "/") {
+		errs = append(errs, field.Invalid(path.Child("path"), template.Path, "must start with /"))
+	}
+	if template.Port != nil && template.Port.String() == "" {
+		errs = append(errs, field.Invalid(path.Child("port"), template.Port.String(), "must be a port name or number"))
+	}
+	if tls := template.TLS; tls != nil {
+		tlsPath := path.Child("tls")
+		if !slices.Contains(tlsTerminations, string(tls.Termination)) {
+			errs = append(errs, field.NotSupported(tlsPath.Child("termination"), tls.Termination, tlsTerminations))
+		}
+		if tls.InsecureEdgeTerminationPolicy != "" && !slices.Contains(insecureEdgeTerminationPolicies, string(tls.InsecureEdgeTerminationPolicy)) {
+			errs = append(errs, field.NotSupported(tlsPath.Child("insecureEdgeTerminationPolicy"), tls.InsecureEdgeTerminationPolicy, insecureEdgeTerminationPolicies))
+		}
+	}
+	for key := range template.Annotations {
+		if strings.HasPrefix(key, annotationPrefix) {
+			errs = append(errs, field.Forbidden(path.Child("annotations").Key(key), "the annotation is reserved for the plugin"))
+		}
+	}
+	return errs
+}
+
+// validateRouteTemplateHost checks that the host template renders a valid and distinct host for each route,
+// since the router only admits the first route claiming a host.
+func validateRouteTemplateHost(template *RouteTemplate, routes []string, path *field.Path) field.ErrorList {
+	if _, err := template.hostTemplate(); err != nil {
+		return field.ErrorList{field.Invalid(path, template.Host, err.Error())}
+	}
+
+	var errs field.ErrorList
+	hosts := map[string]string{}
+	for _, route := range routes {
+		namespace, name := splitRouteReference(route, "")
+		host, err := template.host(routeHostData{Route: name, Namespace: namespace})
+		if err != nil {
+			return append(errs, field.Invalid(path, template.Host, err.Error()))
+		}
+		if host == "" {
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(host) {
+			errs = append(errs, field.Invalid(path, template.Host, msg))
+		}
+		if other, ok := hosts[host]; ok {
+			errs = append(errs, field.Invalid(path, template.Host, "renders the host "+host+" for both routes "+other+" and "+route+
+				", use {{ .Route }} to give each route its own host"))
+		}
+		hosts[host] = route
+	}
+	return errs
+}
+
+// createRoute creates the Route of the target from the route template, returning the existing Route
+// if another reconciliation created it first.
+func (r *RpcPlugin) createRoute(ctx context.Context, target routeTarget, rollout *v1alpha1.Rollout, template *RouteTemplate) (*routev1.Route, error) {
+	route, err := routeFromTemplate(target, rollout, template)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHost(r.globalConfig().AllowedHostDomains, rollout, "routeTemplate", route.Spec.Host); err != nil {
+		return nil, err
+	}
+	slog.Info("creating route from the route template", slog.String("route", target.String()))
+	created, err := target.client.RouteV1().Routes(target.Namespace).Create(ctx, route, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		return r.getRoute(ctx, target)
+	}
+	return created, err
+}
+
+// routeFromTemplate returns the Route of the target described by the template, sending all traffic to the stable service.
+func routeFromTemplate(target routeTarget, rollout *v1alpha1.Rollout, template *RouteTemplate) (*routev1.Route, error) {
+	host, err := template.host(routeHostData{Route: target.Name, Namespace: target.Namespace, Rollout: rollout.Name})
+	if err != nil {
+		return nil, newError(ReasonInvalidConfig, err, "invalid routeTemplate.host")
+	}
+	if msgs := validation.IsDNS1123Subdomain(host); host != "" && len(msgs) > 0 {
+		return nil, newError(ReasonInvalidConfig, nil, "invalid route host %q: %s", host, strings.Join(msgs, ", "))
+	}
+
+	weight := int32(100)
+	route := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        target.Name,
+			Namespace:   target.Namespace,
+			Annotations: maps.Clone(template.Annotations),
+		},
+		Spec: routev1.RouteSpec{
+			Host: host,
+			Path: template.Path,
+			TLS:  template.TLS.DeepCopy(),
+			To: routev1.RouteTargetReference{
+				Kind:   "Service",
+				Name:   rollout.Spec.Strategy.Canary.StableService,
+				Weight: &weight,
+			},
+		},
+	}
+	if template.Port != nil {
+		route.Spec.Port = &routev1.RoutePort{TargetPort: *template.Port}
+	}
+	setOwner(route, rollout)
+	return route, nil
+}
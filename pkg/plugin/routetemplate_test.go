@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Test the route template", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+		template    *RouteTemplate
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		r = &RpcPlugin{routeClient: routeClient}
+
+		port := intstr.FromString("http")
+		template = &RouteTemplate{
+			Host:        "new.example.com",
+			Path:        "/api",
+			Port:        &port,
+			TLS:         &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge},
+			Annotations: map[string]string{"haproxy.router.openshift.io/balance": "roundrobin"},
+		}
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, "new-route")
+		rollout.UID = "rollout-uid"
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{"new-route"}, RouteTemplate: template})
+	})
+
+	It("should create a missing route owned by the rollout", func() {
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, "new-route", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(route.Spec.Host).To(Equal("new.example.com"))
+		Expect(route.Spec.Path).To(Equal("/api"))
+		Expect(route.Spec.Port.TargetPort).To(Equal(intstr.FromString("http")))
+		Expect(route.Spec.TLS.Termination).To(Equal(routev1.TLSTerminationEdge))
+		Expect(route.Annotations).To(HaveKeyWithValue("haproxy.router.openshift.io/balance", "roundrobin"))
+		Expect(route.Spec.To.Name).To(Equal(mocks.StableServiceName))
+		Expect(*route.Spec.To.Weight).To(Equal(int32(70)))
+		Expect(route.Spec.AlternateBackends).To(HaveLen(1))
+		Expect(route.Spec.AlternateBackends[0].Name).To(Equal(mocks.CanaryServiceName))
+
+		Expect(route.OwnerReferences).To(HaveLen(1))
+		Expect(route.OwnerReferences[0].Kind).To(Equal("Rollout"))
+		Expect(route.OwnerReferences[0].UID).To(BeEquivalentTo("rollout-uid"))
+	})
+
+	It("should not own a route created in another namespace", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{"shared/new-route"}, RouteTemplate: template})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		route, err := routeClient.RouteV1().Routes("shared").Get(ctx, "new-route", metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(route.OwnerReferences).To(BeEmpty())
+	})
+
+	It("should leave existing routes as they are", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{mocks.RouteName}, RouteTemplate: template})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(route.Spec.Host).To(Equal("http://route.example.com"))
+		Expect(route.OwnerReferences).To(BeEmpty())
+	})
+
+	It("should still fail on a missing route without a template", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{"new-route"}})
+		Expect(r.SetWeight(rollout, 30, nil).Error()).To(HavePrefix(string(ReasonRouteNotFound)))
+	})
+
+	DescribeTable("should validate the template",
+		func(template RouteTemplate, expected string) {
+			setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{"new-route"}, RouteTemplate: &template})
+			_, err := getOpenshiftRouting(rollout)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expected))
+		},
+		Entry("invalid host", RouteTemplate{Host: "Not_A_Host"}, "routeTemplate.host: Invalid value"),
+		Entry("invalid host template", RouteTemplate{Host: "{{ .Service }}.example.com"}, "routeTemplate.host: Invalid value"),
+		Entry("relative path", RouteTemplate{Path: "api"}, "routeTemplate.path: Invalid value: \"api\": must start with /"),
+		Entry("unknown termination", RouteTemplate{TLS: &routev1.TLSConfig{Termination: "mtls"}}, "routeTemplate.tls.termination: Unsupported value"),
+		Entry("reserved annotation", RouteTemplate{Annotations: map[string]string{originalAnnotationsKey: "{}"}}, "the annotation is reserved for the plugin"),
+	)
+
+	It("should give each route its own host", func() {
+		template.Host = "{{ .Route }}.apps.example.com"
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{"new-route", "other-route"}, RouteTemplate: template})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+
+		for _, name := range []string{"new-route", "other-route"} {
+			route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(route.Spec.Host).To(Equal(name + ".apps.example.com"))
+		}
+	})
+
+	It("should refuse a host shared by several routes", func() {
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{"new-route", "other-route"}, RouteTemplate: template})
+		_, err := getOpenshiftRouting(rollout)
+		Expect(err).To(MatchError(ContainSubstring("renders the host new.example.com for both routes new-route and other-route")))
+	})
+
+	It("should only create hosts of the allowed domains", func() {
+		r.SetGlobalConfig(&GlobalConfig{AllowedHostDomains: map[string][]string{mocks.Namespace: {"apps.example.com"}}})
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.ErrorString).To(HavePrefix("OpenshiftForbidden: host new.example.com of the routeTemplate of rollout default/" +
+			rollout.Name + " is not in the allowed domains apps.example.com"))
+		_, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, "new-route", metav1.GetOptions{})
+		Expect(err).To(HaveOccurred())
+
+		template.Host = "new.apps.example.com"
+		setPluginConfig(rollout, OpenshiftTrafficRouting{Routes: []string{"new-route"}, RouteTemplate: template})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+	})
+})
@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	pluginTypes "github.com/argoproj/argo-rollouts/utils/plugin/types"
+)
+
+// State is the view the plugin has of the rollouts it served since it started
+type State struct {
+	Rollouts []RolloutState `json:"rollouts"`
+	// Errors counts the errors returned to the rollouts controller by reason
+	Errors map[ErrorReason]int `json:"errors"`
+}
+
+// RolloutState is what the plugin last did for a rollout
+type RolloutState struct {
+	Rollout string `json:"rollout"`
+	// Routes are the managed routes, as <namespace>/<name> prefixed with <cluster>: for remote clusters
+	Routes []string `json:"routes,omitempty"`
+	// CanaryHash is the pod template hash of the canary given to UpdateHash
+	CanaryHash string `json:"canaryHash,omitempty"`
+	// Weight is the canary weight last applied to the routes
+	Weight *int32 `json:"weight,omitempty"`
+	// AppliedAt is when Weight was applied
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	// PendingVerification is set while VerifyWeight has not verified the weight last asked for
+	PendingVerification *PendingVerification `json:"pendingVerification,omitempty"`
+	// LastError is the last error returned for the rollout
+	LastError string `json:"lastError,omitempty"`
+	// LastErrorAt is when LastError was returned
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// PendingVerification is a weight VerifyWeight has not verified yet
+type PendingVerification struct {
+	Weight int32 `json:"weight"`
+	// Since is when the weight was first checked
+	Since time.Time `json:"since"`
+	// Attempts is the number of calls of VerifyWeight for the weight
+	Attempts int `json:"attempts"`
+}
+
+// stateTracker records the calls of the rollouts controller for the debug endpoint
+type stateTracker struct {
+	mu       sync.Mutex
+	rollouts map[string]*RolloutState
+	errors   map[ErrorReason]int
+	// now returns the current time, time.Now when nil
+	now func() time.Time
+}
+
+func (t *stateTracker) rollout(rollout *v1alpha1.Rollout) *RolloutState {
+	if t.rollouts == nil {
+		t.rollouts = map[string]*RolloutState{}
+	}
+	key := rolloutKey(rollout)
+	state, ok := t.rollouts[key]
+	if !ok {
+		state = &RolloutState{Rollout: key}
+		t.rollouts[key] = state
+	}
+	return state
+}
+
+func (t *stateTracker) timestamp() *time.Time {
+	now := time.Now()
+	if t.now != nil {
+		now = t.now()
+	}
+	return &now
+}
+
+// recordError counts the error and keeps it as the last one of the rollout.
+func (t *stateTracker) recordError(state *RolloutState, rpcErr pluginTypes.RpcError) {
+	if !rpcErr.HasError() {
+		return
+	}
+	if t.errors == nil {
+		t.errors = map[ErrorReason]int{}
+	}
+	t.errors[rpcErrorReason(rpcErr)]++
+	state.LastError = rpcErr.ErrorString
+	state.LastErrorAt = t.timestamp()
+}
+
+// setRoutes records the routes managed for the rollout.
+func (t *stateTracker) setRoutes(rollout *v1alpha1.Rollout, targets []routeTarget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	routes := make([]string, 0, len(targets))
+	for _, target := range targets {
+		routes = append(routes, target.String())
+	}
+	t.rollout(rollout).Routes = routes
+}
+
+// setWeight records the result of SetWeight.
+func (t *stateTracker) setWeight(rollout *v1alpha1.Rollout, canaryHash string, weight int32, rpcErr pluginTypes.RpcError) {
+	if rollout == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.rollout(rollout)
+	state.CanaryHash = canaryHash
+	if rpcErr.HasError() {
+		t.recordError(state, rpcErr)
+		return
+	}
+	state.Weight = &weight
+	state.AppliedAt = t.timestamp()
+}
+
+// verifyWeight records the result of VerifyWeight.
+func (t *stateTracker) verifyWeight(rollout *v1alpha1.Rollout, weight int32, verified pluginTypes.RpcVerified, rpcErr pluginTypes.RpcError) {
+	if rollout == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.rollout(rollout)
+	t.recordError(state, rpcErr)
+	if verified == pluginTypes.Verified {
+		state.PendingVerification = nil
+		return
+	}
+	if state.PendingVerification == nil || state.PendingVerification.Weight != weight {
+		state.PendingVerification = &PendingVerification{Weight: weight, Since: *t.timestamp()}
+	}
+	state.PendingVerification.Attempts++
+}
+
+// removeManagedRoutes forgets the rollout once its routes were restored.
+func (t *stateTracker) removeManagedRoutes(rollout *v1alpha1.Rollout, rpcErr pluginTypes.RpcError) {
+	if rollout == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rpcErr.HasError() {
+		t.recordError(t.rollout(rollout), rpcErr)
+		return
+	}
+	delete(t.rollouts, rolloutKey(rollout))
+}
+
+// snapshot returns a copy of the recorded state, sorted by rollout.
+func (t *stateTracker) snapshot() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := State{Rollouts: make([]RolloutState, 0, len(t.rollouts)), Errors: map[ErrorReason]int{}}
+	for _, rollout := range t.rollouts {
+		copied := *rollout
+		if rollout.PendingVerification != nil {
+			pending := *rollout.PendingVerification
+			copied.PendingVerification = &pending
+		}
+		state.Rollouts = append(state.Rollouts, copied)
+	}
+	sort.Slice(state.Rollouts, func(i, j int) bool { return state.Rollouts[i].Rollout < state.Rollouts[j].Rollout })
+	for reason, count := range t.errors {
+		state.Errors[reason] = count
+	}
+	return state
+}
+
+// State returns the view the plugin has of the rollouts it served since it started.
+func (r *RpcPlugin) State() State {
+	return r.state.snapshot()
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RouteStatus describes the live traffic split of a managed Route
+type RouteStatus struct {
+	// Route is the route as <namespace>/<name>, prefixed with <cluster>: for remote clusters
+	Route string
+	// Host is the host of the route, as generated by the router when not set
+	Host string
+	// Backends are the default backend followed by the alternate backends
+	Backends []BackendWeight
+	// Admissions is the admission state of the route by the configured router shards
+	Admissions []ShardAdmission
+	// Drift lists the differences between the route and the state the rollout expects
+	Drift []string
+	// Err is set when the route could not be read
+	Err error
+}
+
+// BackendWeight is the weight of a backend of a Route
+type BackendWeight struct {
+	Kind   string
+	Name   string
+	Weight int32
+	// Percent is the share of the traffic of the route sent to the backend
+	Percent float64
+}
+
+// GetRollout returns the rollout with the given namespace and name.
+func (r *RpcPlugin) GetRollout(ctx context.Context, namespace, name string) (*v1alpha1.Rollout, error) {
+	rollout, err := r.rolloutClient.ArgoprojV1alpha1().Rollouts(namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, newError(ReasonInvalidConfig, nil, "rollout %q not found in namespace %q", name, namespace)
+	}
+	return rollout, err
+}
+
+// Status reports the live traffic split of every route managed for the rollout.
+// Routes that cannot be read are reported with their error.
+func (r *RpcPlugin) Status(ctx context.Context, rollout *v1alpha1.Rollout) ([]RouteStatus, error) {
+	openshift, err := r.rolloutRouting(rollout)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := r.routeTargets(ctx, rollout, openshift)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]RouteStatus, 0, len(targets))
+	for _, target := range targets {
+		status := RouteStatus{Route: target.String()}
+		route, err := r.getRoute(ctx, target)
+		if err != nil {
+			status.Err = withCluster(err, target.Cluster)
+			statuses = append(statuses, status)
+			continue
+		}
+
+		status.Host = route.Spec.Host
+		if status.Host == "" && len(route.Status.Ingress) > 0 {
+			status.Host = route.Status.Ingress[0].Host
+		}
+		status.Backends = backendWeights(route)
+		status.Admissions = admissionStatus(route, openshift.RouterShards)
+		status.Drift = routeDrift(route, rollout, openshift)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// backendWeights returns the weights of the backends of the route along with their share of the traffic.
+func backendWeights(route *routev1.Route) []BackendWeight {
+	backends := append([]routev1.RouteTargetReference{route.Spec.To}, route.Spec.AlternateBackends...)
+	var total int32
+	for _, backend := range backends {
+		total += weightOf(backend)
+	}
+
+	weights := make([]BackendWeight, 0, len(backends))
+	for _, backend := range backends {
+		weight := BackendWeight{Kind: backend.Kind, Name: backend.Name, Weight: weightOf(backend)}
+		if total > 0 {
+			weight.Percent = float64(weight.Weight) * 100 / float64(total)
+		}
+		weights = append(weights, weight)
+	}
+	return weights
+}
+
+// routeDrift returns the differences between the backends of the route and the services and weights of the rollout.
+func routeDrift(route *routev1.Route, rollout *v1alpha1.Rollout, openshift *OpenshiftTrafficRouting) []string {
+	drift := backendDrift(route, rollout)
+	if weights := rollout.Status.Canary.Weights; weights != nil {
+		stableWeight, canaryWeight := routeWeights(route)
+		desiredStable, desiredCanary := openshift.scaledWeights(weights.Canary.Weight)
+		if canaryWeight != desiredCanary || stableWeight != desiredStable {
+			drift = append(drift, fmt.Sprintf("the route sends %d to the stable service and %d to the canary, the rollout set %d and %d",
+				stableWeight, canaryWeight, desiredStable, desiredCanary))
+		}
+	}
+	return drift
+}
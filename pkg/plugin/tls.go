@@ -0,0 +1,188 @@
+package plugin
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"sync"
+
+	"log/slog"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// TLSPolicy selects what the plugin does with TLS settings that are known to break a canary
+type TLSPolicy string
+
+const (
+	// TLSPolicyWarn logs the problem and updates the route anyway
+	TLSPolicyWarn TLSPolicy = "Warn"
+	// TLSPolicyFail refuses to send traffic to the canary
+	TLSPolicyFail TLSPolicy = "Fail"
+)
+
+// annotations set on a Service to have the service CA issue its serving certificate into a Secret
+var servingCertSecretAnnotations = []string{
+	"service.beta.openshift.io/serving-cert-secret-name",
+	"service.alpha.openshift.io/serving-cert-secret-name",
+}
+
+func validateTLSPolicy(policy TLSPolicy, path *field.Path) field.ErrorList {
+	policies := []string{string(TLSPolicyWarn), string(TLSPolicyFail)}
+	if policy != "" && !slices.Contains(policies, string(policy)) {
+		return field.ErrorList{field.NotSupported(path, policy, policies)}
+	}
+	return nil
+}
+
+// ServingCertSecretName returns the Secret the service CA issues the serving certificate of the Service into,
+// or an empty string when the Service does not ask for one.
+func ServingCertSecretName(service *corev1.Service) string {
+	for _, annotation := range servingCertSecretAnnotations {
+		if name, ok := service.Annotations[annotation]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// tlsProblems remembers the last TLS problem found on each route, so that it is only logged when it changes
+type tlsProblems struct {
+	mu       sync.Mutex
+	problems map[string]string
+}
+
+// changed records the problem of the route and tells whether it differs from the previous one.
+func (p *tlsProblems) changed(route string, problem string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.problems[route] == problem {
+		return false
+	}
+	if p.problems == nil {
+		p.problems = map[string]string{}
+	}
+	if problem == "" {
+		delete(p.problems, route)
+	} else {
+		p.problems[route] = problem
+	}
+	return true
+}
+
+// checkTLS inspects the TLS settings of a route about to send traffic to the canary service.
+// Problems are logged when they change, or returned as an error with the Fail policy. Passthrough
+// routes only balance connections approximately, which never fails the canary.
+func (r *RpcPlugin) checkTLS(ctx context.Context, target routeTarget, route *routev1.Route, canaryService string, openshift *OpenshiftTrafficRouting) error {
+	var problem string
+	tls := route.Spec.TLS
+	if tls == nil {
+		r.logTLSProblem(target, "", problem)
+		return nil
+	}
+
+	switch tls.Termination {
+	case routev1.TLSTerminationPassthrough:
+		problem = "the router forwards passthrough traffic per TLS connection using SNI, " +
+			"so weights apply to connections rather than requests and long-lived connections stay on their backend"
+	case routev1.TLSTerminationReencrypt:
+		// without a destination CA the router trusts the service CA, which signs every serving certificate
+		if tls.DestinationCACertificate == "" || !openshift.VerifyCanaryCertificate {
+			break
+		}
+		if target.Cluster != "" {
+			slog.Debug("skipping the canary certificate check of a route in a remote cluster", slog.String("route", target.String()))
+			break
+		}
+		problem = r.checkCanaryCertificate(ctx, route.Namespace, canaryService, tls.DestinationCACertificate)
+	}
+
+	if problem != "" && openshift.TLSPolicy == TLSPolicyFail && tls.Termination != routev1.TLSTerminationPassthrough {
+		r.tlsProblems.changed(target.String(), problem)
+		return &PluginError{
+			Reason:  ReasonInvalidConfig,
+			Message: fmt.Sprintf("route %s/%s uses %s TLS termination: %s", route.Namespace, route.Name, tls.Termination, problem),
+			Hint:    "fix the TLS settings of the route or the canary Service, or set tlsPolicy: Warn",
+		}
+	}
+	r.logTLSProblem(target, tls.Termination, problem)
+	return nil
+}
+
+// logTLSProblem logs the TLS problem of the route when it differs from the last one found.
+func (r *RpcPlugin) logTLSProblem(target routeTarget, termination routev1.TLSTerminationType, problem string) {
+	if !r.tlsProblems.changed(target.String(), problem) {
+		return
+	}
+	if problem == "" {
+		slog.Info("the TLS settings of the route no longer break the canary", slog.String("route", target.String()))
+		return
+	}
+	slog.Warn("the TLS settings of the route may break the canary", slog.String("route", target.String()),
+		slog.String("termination", string(termination)), slog.String("problem", problem))
+}
+
+// checkCanaryCertificate verifies that the serving certificate of the canary Service is trusted by the
+// destination CA of a re-encrypt route, returning a description of the problem if it is not.
+func (r *RpcPlugin) checkCanaryCertificate(ctx context.Context, namespace, canaryService, destinationCA string) string {
+	service, err := r.kubeClient.CoreV1().Services(namespace).Get(ctx, canaryService, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Sprintf("the canary Service %q does not exist", canaryService)
+		}
+		return fmt.Sprintf("unable to read the canary Service %q: %v", canaryService, err)
+	}
+
+	secretName := ServingCertSecretName(service)
+	if secretName == "" {
+		return fmt.Sprintf("the canary Service %q has no serving certificate Secret annotation to check", canaryService)
+	}
+
+	secret, err := r.kubeClient.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Sprintf("unable to read the serving certificate Secret %q of the canary Service: %v", secretName, err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(destinationCA)) {
+		return "the destinationCACertificate of the route holds no valid PEM certificate"
+	}
+
+	var certs []*x509.Certificate
+	rest := secret.Data[corev1.TLSCertKey]
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Sprintf("invalid certificate in Secret %q: %v", secretName, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return fmt.Sprintf("the Secret %q holds no certificate under %s", secretName, corev1.TLSCertKey)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		DNSName:       fmt.Sprintf("%s.%s.svc", canaryService, namespace),
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return fmt.Sprintf("the serving certificate of the canary Service %q is not trusted by the destinationCACertificate of the route: %v", canaryService, err)
+	}
+	return ""
+}
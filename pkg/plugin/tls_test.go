@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/argoproj-labs/rollouts-plugin-trafficrouter-openshift/pkg/mocks"
+	"github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+// newCertificate returns a PEM certificate for the given DNS name signed by parent, or self-signed when parent is nil
+func newCertificate(dnsName string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: dnsName},
+		DNSNames:              []string{dnsName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+var _ = Describe("Test TLS compatibility checks", func() {
+	var (
+		ctx         context.Context
+		routeClient *fake.Clientset
+		kubeClient  *k8sfake.Clientset
+		r           *RpcPlugin
+		rollout     *v1alpha1.Rollout
+		caPEM       []byte
+		servingPEM  []byte
+	)
+
+	setTLS := func(tls *routev1.TLSConfig) {
+		route, err := routeClient.RouteV1().Routes(mocks.Namespace).Get(ctx, mocks.RouteName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		route.Spec.TLS = tls
+		_, err = routeClient.RouteV1().Routes(mocks.Namespace).Update(ctx, route, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ca, caKey, pemCA := newCertificate("ca", true, nil, nil)
+		_, _, pemServing := newCertificate(mocks.CanaryServiceName+"."+mocks.Namespace+".svc", false, ca, caKey)
+		caPEM, servingPEM = pemCA, pemServing
+
+		routeClient = fake.NewSimpleClientset(mocks.MakeObjects()...)
+		kubeClient = k8sfake.NewSimpleClientset(
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+				Name:        mocks.CanaryServiceName,
+				Namespace:   mocks.Namespace,
+				Annotations: map[string]string{servingCertSecretAnnotations[0]: "canary-tls"},
+			}},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "canary-tls", Namespace: mocks.Namespace},
+				Data:       map[string][]byte{corev1.TLSCertKey: servingPEM},
+			},
+		)
+		r = &RpcPlugin{routeClient: routeClient, kubeClient: kubeClient}
+
+		rollout = newRollout(mocks.StableServiceName, mocks.CanaryServiceName, mocks.RouteName)
+		setPluginConfig(rollout, OpenshiftTrafficRouting{
+			Routes:                  []string{mocks.RouteName},
+			TLSPolicy:               TLSPolicyFail,
+			VerifyCanaryCertificate: true,
+		})
+	})
+
+	It("should accept a canary certificate trusted by the destination CA", func() {
+		setTLS(&routev1.TLSConfig{Termination: routev1.TLSTerminationReencrypt, DestinationCACertificate: string(caPEM)})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+	})
+
+	It("should reject a canary certificate signed by another CA", func() {
+		_, _, otherCA := newCertificate("other-ca", true, nil, nil)
+		setTLS(&routev1.TLSConfig{Termination: routev1.TLSTerminationReencrypt, DestinationCACertificate: string(otherCA)})
+		rpcErr := r.SetWeight(rollout, 30, nil)
+		Expect(rpcErr.Error()).To(HavePrefix(string(ReasonInvalidConfig) + ": route default/argo-rollouts uses reencrypt TLS termination"))
+		Expect(rpcErr.Error()).To(ContainSubstring("is not trusted by the destinationCACertificate"))
+	})
+
+	It("should reject a canary Service without a serving certificate", func() {
+		service, err := kubeClient.CoreV1().Services(mocks.Namespace).Get(ctx, mocks.CanaryServiceName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		service.Annotations = nil
+		_, err = kubeClient.CoreV1().Services(mocks.Namespace).Update(ctx, service, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		setTLS(&routev1.TLSConfig{Termination: routev1.TLSTerminationReencrypt, DestinationCACertificate: string(caPEM)})
+		Expect(r.SetWeight(rollout, 30, nil).Error()).To(ContainSubstring("has no serving certificate Secret annotation"))
+	})
+
+	It("should trust the service CA when the route has no destination CA", func() {
+		setTLS(&routev1.TLSConfig{Termination: routev1.TLSTerminationReencrypt})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+	})
+
+	It("should only warn about passthrough routes, even with the Fail policy", func() {
+		setTLS(&routev1.TLSConfig{Termination: routev1.TLSTerminationPassthrough})
+		Expect(r.SetWeight(rollout, 30, nil).HasError()).To(BeFalse())
+		Expect(r.tlsProblems.problems).To(HaveKeyWithValue(mocks.Namespace+"/"+mocks.RouteName, ContainSubstring("TLS connection")))
+	})
+
+	It("should only report a problem when it changes", func() {
+		Expect(r.tlsProblems.changed("default/a", "")).To(BeFalse())
+		Expect(r.tlsProblems.changed("default/a", "expired")).To(BeTrue())
+		Expect(r.tlsProblems.changed("default/a", "expired")).To(BeFalse())
+		Expect(r.tlsProblems.changed("default/a", "")).To(BeTrue())
+		Expect(r.tlsProblems.problems).To(BeEmpty())
+	})
+})
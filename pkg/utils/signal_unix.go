@@ -0,0 +1,11 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// toggleSignal switches the logger between the debug and the configured level
+var toggleSignal os.Signal = syscall.SIGUSR1
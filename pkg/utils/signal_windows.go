@@ -0,0 +1,8 @@
+//go:build windows
+
+package utils
+
+import "os"
+
+// toggleSignal is not available on Windows
+var toggleSignal os.Signal
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"os"
+	"os/signal"
+	"sync"
 
 	"log/slog"
 
@@ -9,17 +11,73 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-func NewKubeConfig() (*rest.Config, error) {
+// KubeConfigOptions customizes the client configuration used by the plugin.
+// The zero value uses the default loading rules without any override.
+type KubeConfigOptions struct {
+	// Kubeconfig is the path of the kubeconfig file. When empty, the default loading rules apply:
+	// $KUBECONFIG, then ~/.kube/config, then the in-cluster config.
+	Kubeconfig string
+	// Context is the kubeconfig context to use instead of the current one
+	Context string
+	// QPS is the maximum queries per second to the API server, the client-go default applies when 0
+	QPS float32
+	// Burst is the maximum burst of queries to the API server, the client-go default applies when 0
+	Burst int
+	// UserAgent is the user agent sent to the API server
+	UserAgent string
+	// Impersonate is the user to act as, e.g. system:serviceaccount:<namespace>:<name>
+	Impersonate string
+	// ImpersonateGroups are the groups to act as
+	ImpersonateGroups []string
+}
+
+func NewKubeConfig(opts KubeConfigOptions) (*rest.Config, error) {
 	rules := clientcmd.NewDefaultClientConfigLoadingRules()
-	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
-	return config.ClientConfig()
+	rules.ExplicitPath = opts.Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.Context}
+	overrides.AuthInfo.Impersonate = opts.Impersonate
+	overrides.AuthInfo.ImpersonateGroups = opts.ImpersonateGroups
+
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	cfg, err := config.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// in-cluster configs ignore the auth overrides, so impersonation is set on the result as well
+	if opts.Impersonate != "" {
+		cfg.Impersonate.UserName = opts.Impersonate
+		cfg.Impersonate.Groups = opts.ImpersonateGroups
+	}
+	if opts.QPS > 0 {
+		cfg.QPS = opts.QPS
+	}
+	if opts.Burst > 0 {
+		cfg.Burst = opts.Burst
+	}
+	if opts.UserAgent != "" {
+		cfg.UserAgent = opts.UserAgent
+	}
+	return cfg, nil
 }
 
+var (
+	// logLevel is the level of the logger set up by InitLogger, which can be changed while the plugin runs
+	logLevel slog.LevelVar
+
+	levelMu sync.Mutex
+	// configuredLevel is the level last set by InitLogger or SetLogLevel, which ToggleDebug switches back to
+	configuredLevel slog.Level
+)
+
 func InitLogger(lvl slog.Level) {
-	lvlVar := &slog.LevelVar{}
-	lvlVar.Set(lvl)
+	logLevel.Set(lvl)
+	levelMu.Lock()
+	configuredLevel = lvl
+	levelMu.Unlock()
 	opts := slog.HandlerOptions{
-		Level: lvlVar,
+		Level: &logLevel,
 	}
 
 	attrs := []slog.Attr{
@@ -30,3 +88,50 @@ func InitLogger(lvl slog.Level) {
 	l := slog.New(slog.NewTextHandler(os.Stderr, &opts).WithAttrs(attrs))
 	slog.SetDefault(l)
 }
+
+// LogLevel returns the current level of the logger.
+func LogLevel() slog.Level {
+	return logLevel.Level()
+}
+
+// SetLogLevel changes the level of the logger without restarting the plugin.
+func SetLogLevel(lvl slog.Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	configuredLevel = lvl
+	setLevel(lvl)
+}
+
+// ToggleDebug switches the logger to the debug level, or back to the configured level when it is already at debug,
+// and returns the new level.
+func ToggleDebug() slog.Level {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	lvl := slog.LevelDebug
+	if logLevel.Level() == slog.LevelDebug && configuredLevel != slog.LevelDebug {
+		lvl = configuredLevel
+	}
+	setLevel(lvl)
+	return lvl
+}
+
+// ToggleDebugOnSignal calls ToggleDebug whenever the process receives SIGUSR1, which Windows does not support.
+func ToggleDebugOnSignal() {
+	if toggleSignal == nil {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, toggleSignal)
+	for range signals {
+		ToggleDebug()
+	}
+}
+
+func setLevel(lvl slog.Level) {
+	if logLevel.Level() == lvl {
+		return
+	}
+	logLevel.Set(lvl)
+	// logged as a warning to show at every level but error
+	slog.Warn("log level changed", slog.String("level", lvl.String()))
+}